@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	eventExpiryWarning = "expiry_warning"
+
+	// defaultExpiryDays es la antelación del aviso de expiración si el perfil
+	// no define thresholds.expiry_days
+	defaultExpiryDays = 7
+)
+
+// AccountState es el último estado conocido de la cuenta de un perfil
+type AccountState struct {
+	Username       string        `json:"username"`
+	Status         AccountStatus `json:"status"`
+	Credits        float64       `json:"credits"`
+	ExpirationDate *time.Time    `json:"expiration_date,omitempty"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+func getAccountStatePathFor(profile string) (string, error) {
+	profileDir, err := getProfileDirFor(profile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(profileDir, "account.json"), nil
+}
+
+// LoadAccountStateFor devuelve el último estado conocido de un perfil, o nil
+// si nunca se ha consultado
+func LoadAccountStateFor(profile string) (*AccountState, error) {
+	statePath, err := getAccountStatePathFor(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var state AccountState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%s: %w", statePath, err)
+	}
+	return &state, nil
+}
+
+// rememberAccountState guarda el estado de la cuenta del perfil activo tras
+// una consulta al portal
+func rememberAccountState(username string, userInfo *UserInfo) error {
+	statePath, err := getAccountStatePathFor(currentProfile)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(AccountState{
+		Username:       username,
+		Status:         userInfo.Status,
+		Credits:        userInfo.Credits,
+		ExpirationDate: userInfo.ExpirationDate,
+		UpdatedAt:      time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(statePath, data, 0600)
+}
+
+// expiryDays devuelve con cuántos días de antelación avisar de la expiración
+func (c *Config) expiryDays() int {
+	if c.Thresholds.ExpiryDays > 0 {
+		return c.Thresholds.ExpiryDays
+	}
+	return defaultExpiryDays
+}
+
+// daysUntil devuelve los días completos que faltan hasta date
+func daysUntil(date time.Time, now time.Time) int {
+	return int(math.Floor(date.Sub(now).Hours() / 24))
+}
+
+// expiryWarning devuelve un aviso si la cuenta expira dentro de days días o ya
+// ha expirado
+func (s *AccountState) expiryWarning(days int, now time.Time) (string, bool) {
+	if s == nil || s.ExpirationDate == nil {
+		return "", false
+	}
+
+	left := daysUntil(*s.ExpirationDate, now)
+	date := formatExpirationDate(s.ExpirationDate)
+	switch {
+	case s.ExpirationDate.Before(now):
+		return fmt.Sprintf("La cuenta %s expiró el %s con %.2f CUP de saldo", s.Username, date, s.Credits), true
+	case left < days:
+		return fmt.Sprintf("La cuenta %s expira el %s (en %d días): se perderán %.2f CUP de saldo",
+			s.Username, date, left, s.Credits), true
+	}
+	return "", false
+}
+
+// warnExpiry muestra el aviso de expiración del perfil activo y lo notifica a
+// los hooks
+func warnExpiry(config *Config) {
+	state, err := LoadAccountStateFor(currentProfile)
+	if err != nil || state == nil {
+		return
+	}
+
+	message, ok := state.expiryWarning(config.expiryDays(), time.Now())
+	if !ok {
+		return
+	}
+
+	fmt.Printf("⚠️  %s\n", message)
+	fireEvent(config, eventExpiryWarning, expiryHookVars(state, message))
+}
+
+func expiryHookVars(state *AccountState, message string) map[string]string {
+	return map[string]string{
+		"USERNAME":   state.Username,
+		"EXPIRATION": formatExpirationDate(state.ExpirationDate),
+		"DAYS_LEFT":  fmt.Sprintf("%d", daysUntil(*state.ExpirationDate, time.Now())),
+		"CREDITS":    fmt.Sprintf("%.2f", state.Credits),
+		"MESSAGE":    message,
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	eventAuditDiscrepancy = "audit_discrepancy"
+
+	// defaultAuditTolerance es la diferencia en CUP que se acepta entre el
+	// cobro esperado y el descontado si el perfil no define audit.tolerance
+	defaultAuditTolerance = 0.5
+)
+
+// SessionAudit contiene las mediciones del portal al inicio y al final de
+// una sesión. Los campos nulos no se pudieron medir.
+type SessionAudit struct {
+	CreditsBefore    *float64 `json:"credits_before,omitempty"`
+	CreditsAfter     *float64 `json:"credits_after,omitempty"`
+	RemainingAtStart *int64   `json:"remaining_at_start,omitempty"`
+	RemainingAtEnd   *int64   `json:"remaining_at_end,omitempty"`
+}
+
+// auditReport es el resultado de comparar las mediciones de una sesión con la
+// tarifa y la duración real
+type auditReport struct {
+	Record        *SessionRecord
+	Duration      time.Duration
+	ExpectedCost  float64
+	ChargedCost   *float64
+	PortalTime    *time.Duration
+	Discrepancies []string
+}
+
+// MarshalJSON expresa las duraciones del informe en segundos
+func (r auditReport) MarshalJSON() ([]byte, error) {
+	var portalSeconds *int64
+	if r.PortalTime != nil {
+		seconds := int64(r.PortalTime.Seconds())
+		portalSeconds = &seconds
+	}
+	discrepancies := r.Discrepancies
+	if discrepancies == nil {
+		discrepancies = []string{}
+	}
+	return json.Marshal(struct {
+		Session         *SessionRecord `json:"session"`
+		DurationSeconds int64          `json:"duration_seconds"`
+		ExpectedCost    float64        `json:"expected_cost"`
+		ChargedCost     *float64       `json:"charged_cost,omitempty"`
+		PortalSeconds   *int64         `json:"portal_seconds,omitempty"`
+		Discrepancies   []string       `json:"discrepancies"`
+	}{r.Record, int64(r.Duration.Seconds()), r.ExpectedCost, r.ChargedCost, portalSeconds, discrepancies})
+}
+
+// auditTolerance devuelve la diferencia aceptada en CUP
+func (c *Config) auditTolerance() float64 {
+	if c.Audit.Tolerance > 0 {
+		return c.Audit.Tolerance
+	}
+	return defaultAuditTolerance
+}
+
+// measureCredits consulta el saldo en el portal para la auditoría. Usa un
+// cliente propio para no mezclar sus cookies con las de la sesión.
+func measureCredits(config *Config, creds *Credentials) *float64 {
+	client, err := NewClient(config)
+	if err != nil {
+		return nil
+	}
+	userInfo, err := client.GetUserInfo(creds.Username, creds.Password)
+	if err != nil {
+		fmt.Printf("⚠️  Auditoría: no se pudo consultar el saldo: %v\n", err)
+		return nil
+	}
+	credits := userInfo.Credits
+	return &credits
+}
+
+// measureRemainingTime consulta el tiempo restante de la sesión para la
+// auditoría
+func measureRemainingTime(session *Session) *int64 {
+	remaining, err := session.GetRemainingTime()
+	if err != nil {
+		fmt.Printf("⚠️  Auditoría: no se pudo consultar el tiempo restante: %v\n", err)
+		return nil
+	}
+	seconds := int64(remaining.Seconds())
+	return &seconds
+}
+
+// auditEnabled indica si la sesión que empieza debe auditarse
+func auditEnabled(config *Config, flagValue bool) bool {
+	return flagValue || config.Audit.Enabled
+}
+
+// analyzeAudit compara el cobro de una sesión cerrada con lo esperado según
+// su tarifa y su duración
+func analyzeAudit(record *SessionRecord, tolerance float64) auditReport {
+	report := auditReport{Record: record}
+	if record.EndedAt == nil {
+		return report
+	}
+
+	report.Duration = record.Duration(*record.EndedAt)
+	report.ExpectedCost = record.Cost
+
+	audit := record.Audit
+	if audit == nil {
+		return report
+	}
+
+	if audit.CreditsBefore != nil && audit.CreditsAfter != nil {
+		charged := *audit.CreditsBefore - *audit.CreditsAfter
+		report.ChargedCost = &charged
+		if diff := charged - report.ExpectedCost; math.Abs(diff) > tolerance {
+			report.Discrepancies = append(report.Discrepancies, fmt.Sprintf(
+				"Se descontaron %.2f CUP, pero %s a %.2f CUP/h corresponden a %.2f CUP (diferencia: %+.2f CUP)",
+				charged, formatDuration(report.Duration), record.HourRate, report.ExpectedCost, diff))
+		}
+	}
+
+	if audit.RemainingAtStart != nil && audit.RemainingAtEnd != nil && record.HourRate > 0 {
+		portalTime := time.Duration(*audit.RemainingAtStart-*audit.RemainingAtEnd) * time.Second
+		report.PortalTime = &portalTime
+		toleranceTime := time.Duration(tolerance / record.HourRate * float64(time.Hour))
+		if diff := portalTime - report.Duration; diff > toleranceTime || -diff > toleranceTime {
+			report.Discrepancies = append(report.Discrepancies, fmt.Sprintf(
+				"El portal descontó %s de tiempo, pero la sesión duró %s",
+				formatDuration(portalTime), formatDuration(report.Duration)))
+		}
+	}
+
+	return report
+}
+
+// print muestra el informe de una sesión en un formato apto para reclamar
+func (r auditReport) print() {
+	record := r.Record
+	fmt.Printf("=== Auditoría de la sesión %s ===\n", record.ID)
+	fmt.Printf("Usuario:            %s\n", record.Username)
+	fmt.Printf("Inicio:             %s\n", record.StartedAt.Format("2006-01-02 15:04:05 -0700"))
+	if record.EndedAt == nil {
+		fmt.Println("Fin:                (sesión abierta)")
+		return
+	}
+	fmt.Printf("Fin:                %s\n", record.EndedAt.Format("2006-01-02 15:04:05 -0700"))
+	fmt.Printf("Duración real:      %s\n", formatDuration(r.Duration))
+	fmt.Printf("Tarifa:             %s (%.2f CUP/h)\n", record.Tariff, record.HourRate)
+	fmt.Printf("Cobro esperado:     %.2f CUP\n", r.ExpectedCost)
+
+	audit := record.Audit
+	if audit == nil {
+		fmt.Println("\nLa sesión no tiene mediciones de auditoría (active audit.enabled)")
+		return
+	}
+	if audit.CreditsBefore != nil {
+		fmt.Printf("Saldo al conectar:  %.2f CUP\n", *audit.CreditsBefore)
+	}
+	if audit.CreditsAfter != nil {
+		fmt.Printf("Saldo al cerrar:    %.2f CUP\n", *audit.CreditsAfter)
+	}
+	if r.ChargedCost != nil {
+		fmt.Printf("Cobro descontado:   %.2f CUP\n", *r.ChargedCost)
+	}
+	if audit.RemainingAtStart != nil {
+		fmt.Printf("Tiempo al conectar: %s\n", formatDuration(time.Duration(*audit.RemainingAtStart)*time.Second))
+	}
+	if audit.RemainingAtEnd != nil {
+		fmt.Printf("Tiempo al cerrar:   %s\n", formatDuration(time.Duration(*audit.RemainingAtEnd)*time.Second))
+	}
+	if r.PortalTime != nil {
+		fmt.Printf("Tiempo descontado:  %s\n", formatDuration(*r.PortalTime))
+	}
+
+	if len(r.Discrepancies) == 0 {
+		fmt.Println("\n✓ Sin discrepancias")
+		return
+	}
+	fmt.Println("\n⚠️  Discrepancias:")
+	for _, discrepancy := range r.Discrepancies {
+		fmt.Printf("  - %s\n", discrepancy)
+	}
+}
+
+// reportAuditDiscrepancies avisa de las discrepancias de una sesión recién
+// cerrada
+func reportAuditDiscrepancies(config *Config, record *SessionRecord) {
+	report := analyzeAudit(record, config.auditTolerance())
+	for _, discrepancy := range report.Discrepancies {
+		fmt.Printf("⚠️  Auditoría: %s\n", discrepancy)
+	}
+	if len(report.Discrepancies) > 0 {
+		fmt.Printf("  Use 'gonauta audit %s' para ver el informe completo\n", record.ID)
+		fireEvent(config, eventAuditDiscrepancy, map[string]string{
+			"USERNAME":   record.Username,
+			"SESSION_ID": record.ID,
+			"MESSAGE":    report.Discrepancies[0],
+		})
+	}
+}
+
+func handleAudit(args []string) {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	onlyFlagged := fs.Bool("flagged", false, "mostrar solo sesiones con discrepancias")
+	parseCommandFlags(fs, args)
+
+	config, err := loadConfigOrEmpty()
+	if err != nil {
+		fmt.Printf("Error cargando configuración: %v\n", err)
+		os.Exit(1)
+	}
+
+	if fs.NArg() > 0 {
+		record := findSessionRecord(fs.Arg(0))
+		if record == nil {
+			fmt.Printf("Error: la sesión %s no está en el historial\n", fs.Arg(0))
+			os.Exit(1)
+		}
+		report := analyzeAudit(record, config.auditTolerance())
+		if config.jsonOutput() {
+			printJSON(report)
+			return
+		}
+		report.print()
+		return
+	}
+
+	records, err := LoadLedger()
+	if err != nil {
+		fmt.Printf("Error leyendo historial: %v\n", err)
+		os.Exit(1)
+	}
+
+	reports := []auditReport{}
+	for i := range records {
+		if records[i].Audit == nil || records[i].EndedAt == nil {
+			continue
+		}
+		report := analyzeAudit(&records[i], config.auditTolerance())
+		if *onlyFlagged && len(report.Discrepancies) == 0 {
+			continue
+		}
+		reports = append(reports, report)
+	}
+
+	if config.jsonOutput() {
+		printJSON(reports)
+		return
+	}
+
+	if len(reports) == 0 {
+		fmt.Println("No hay sesiones auditadas. Active la auditoría con 'gonauta config set audit.enabled true'")
+		return
+	}
+
+	fmt.Printf("%-16s  %-8s  %9s  %9s  %s\n", "ID", "Duración", "Esperado", "Cobrado", "Estado")
+	for _, report := range reports {
+		charged := "-"
+		if report.ChargedCost != nil {
+			charged = fmt.Sprintf("%.2f", *report.ChargedCost)
+		}
+		state := "✓"
+		if len(report.Discrepancies) > 0 {
+			state = fmt.Sprintf("⚠️  %d discrepancias", len(report.Discrepancies))
+		}
+		fmt.Printf("%-16s  %-8s  %9.2f  %9s  %s\n",
+			report.Record.ID, formatDuration(report.Duration), report.ExpectedCost, charged, state)
+	}
+	fmt.Println("\nUse 'gonauta audit <id>' para ver el informe de una sesión")
+}
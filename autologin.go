@@ -0,0 +1,201 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// networkSettleDelay es cuánto se espera tras un cambio de red antes de
+	// buscar el portal, para dar tiempo a que se asigne la dirección IP
+	networkSettleDelay = 5 * time.Second
+
+	defaultDispatcherPath = "/etc/NetworkManager/dispatcher.d/90-gonauta"
+)
+
+// tryAutoLogin inicia sesión con el perfil por defecto si tiene activado el
+// inicio automático, no hay una sesión abierta y el portal de ETECSA pide
+// iniciar sesión, o no se puede saber porque la detección está desactivada o
+// no concluye.
+// Respeta los horarios permitidos y los límites de gasto.
+func tryAutoLogin(logf func(format string, args ...interface{})) {
+	withProfile(defaultProfile, func() {
+		config, err := LoadConfig()
+		if err != nil {
+			logf("Inicio automático: error cargando configuración: %v", err)
+			return
+		}
+		if !config.AutoLogin.Enabled {
+			return
+		}
+		if _, err := LoadSession(); err == nil {
+			logf("Inicio automático: ya hay una sesión activa")
+			return
+		}
+		state, stateErr := detectNetworkState(config)
+		switch state {
+		case NetworkPortal:
+		case NetworkUnknown:
+			// Sin detección, o si no concluye, no se sabe si el portal pide
+			// iniciar sesión: se intenta igualmente
+			logf("Inicio automático: estado de la red desconocido (%v)", stateErr)
+		default:
+			logf("Inicio automático: no se inicia sesión, estado de la red: %s", state)
+			return
+		}
+
+		now := time.Now()
+		if err := checkAllowedHours(config, now); err != nil {
+			logf("Inicio automático: %v", err)
+			return
+		}
+		if reached, block, err := reachedCaps(config, now); err == nil && block {
+			logf("Inicio automático: no se conecta: %s", reached[0])
+			return
+		}
+		creds, err := resolveUnattendedCredentials(config)
+		if err != nil {
+			logf("Inicio automático: %v", err)
+			return
+		}
+
+		if state == NetworkPortal {
+			logf("Portal de ETECSA detectado: iniciando sesión")
+		} else {
+			logf("Inicio automático: iniciando sesión")
+		}
+		if _, err := openSession(config, creds, connectOptions{}); err != nil {
+			logf("Inicio automático: error al iniciar sesión: %v", err)
+		}
+	})
+}
+
+// autoLoginEnabled indica si el perfil por defecto tiene activado el inicio
+// automático
+func autoLoginEnabled() bool {
+	enabled := false
+	withProfile(defaultProfile, func() {
+		if config, err := LoadConfig(); err == nil {
+			enabled = config.AutoLogin.Enabled
+		}
+	})
+	return enabled
+}
+
+// dispatcherScript genera el script de NetworkManager que ejecuta
+// 'gonauta autologin run' como el usuario indicado al activarse una conexión
+func dispatcherScript(executable, username string) string {
+	return fmt.Sprintf(`#!/bin/sh
+# Instalado por 'gonauta autologin install-dispatcher'.
+# Inicia sesión en Nauta al conectarse a una red, si el portal de ETECSA
+# responde. Para desinstalarlo: gonauta autologin remove-dispatcher
+case "$2" in
+    up|dhcp4-change) ;;
+    *) exit 0 ;;
+esac
+
+runuser -u %s -- %s autologin run >/dev/null 2>&1 &
+`, shellQuote(username), shellQuote(executable))
+}
+
+// shellQuote protege un argumento para sh
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// dispatcherUser devuelve el usuario dueño de la configuración. Con sudo es el
+// usuario que lo invocó, no root.
+func dispatcherUser() (string, error) {
+	if name := os.Getenv("SUDO_USER"); name != "" {
+		return name, nil
+	}
+	current, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return current.Username, nil
+}
+
+func printAutoLoginUsage() {
+	fmt.Println("Uso: gonauta autologin <subcomando>")
+	fmt.Println("\nSubcomandos:")
+	fmt.Println("  run                  Iniciar sesión si el portal de ETECSA lo pide")
+	fmt.Println("  install-dispatcher   Instalar el script de NetworkManager (requiere sudo)")
+	fmt.Println("  remove-dispatcher    Eliminar el script de NetworkManager (requiere sudo)")
+	fmt.Println("\nActive el inicio automático con 'gonauta config set autologin.enabled true'.")
+	fmt.Println("'gonauta daemon' lo ejecuta al detectar cambios de red.")
+}
+
+func handleAutoLogin(args []string) {
+	if len(args) == 0 {
+		printAutoLoginUsage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "run":
+		if !autoLoginEnabled() {
+			fmt.Println("El inicio automático está desactivado")
+			fmt.Println("Use 'gonauta config set autologin.enabled true' para activarlo")
+			os.Exit(1)
+		}
+		tryAutoLogin(func(format string, args ...interface{}) {
+			fmt.Printf(format+"\n", args...)
+		})
+
+	case "install-dispatcher":
+		fs := flag.NewFlagSet("autologin install-dispatcher", flag.ContinueOnError)
+		path := fs.String("path", defaultDispatcherPath, "ruta del script")
+		parseCommandFlags(fs, args[1:])
+
+		executable, err := os.Executable()
+		if err == nil {
+			executable, err = filepath.EvalSymlinks(executable)
+		}
+		if err != nil {
+			fmt.Printf("Error localizando el ejecutable: %v\n", err)
+			os.Exit(1)
+		}
+		username, err := dispatcherUser()
+		if err != nil {
+			fmt.Printf("Error obteniendo el usuario: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := os.WriteFile(*path, []byte(dispatcherScript(executable, username)), 0755); err != nil {
+			fmt.Printf("Error instalando el script: %v\n", err)
+			if os.IsPermission(err) {
+				fmt.Println("Ejecute el comando con sudo")
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Script instalado en %s (usuario: %s)\n", *path, username)
+		if !autoLoginEnabled() {
+			fmt.Println("Recuerde activar el inicio automático con 'gonauta config set autologin.enabled true'")
+		}
+
+	case "remove-dispatcher":
+		fs := flag.NewFlagSet("autologin remove-dispatcher", flag.ContinueOnError)
+		path := fs.String("path", defaultDispatcherPath, "ruta del script")
+		parseCommandFlags(fs, args[1:])
+
+		if err := os.Remove(*path); err != nil {
+			fmt.Printf("Error eliminando el script: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Script eliminado de %s\n", *path)
+
+	case "help", "-h", "--help":
+		printAutoLoginUsage()
+
+	default:
+		fmt.Printf("Subcomando desconocido: %s\n\n", args[0])
+		printAutoLoginUsage()
+		os.Exit(1)
+	}
+}
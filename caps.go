@@ -0,0 +1,275 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	eventCapReached = "cap_reached"
+
+	capModeBlock = "block"
+	capModeWarn  = "warn"
+
+	capScopeProfile = "perfil"
+	capScopeGlobal  = "global"
+)
+
+// SpendingCaps son los límites de gasto en CUP por día, semana y mes. Un
+// límite en cero no se aplica.
+type SpendingCaps struct {
+	Daily   float64 `json:"daily,omitempty"`
+	Weekly  float64 `json:"weekly,omitempty"`
+	Monthly float64 `json:"monthly,omitempty"`
+	Mode    string  `json:"mode,omitempty"`
+}
+
+func (c SpendingCaps) validate() error {
+	if c.Daily < 0 || c.Weekly < 0 || c.Monthly < 0 {
+		return errors.New("los límites de gasto no pueden ser negativos")
+	}
+	if c.Mode != "" && c.Mode != capModeBlock && c.Mode != capModeWarn {
+		return fmt.Errorf("modo de límite inválido: %s (use block o warn)", c.Mode)
+	}
+	return nil
+}
+
+// blocks indica si alcanzar el límite impide conectar y cierra la sesión
+func (c SpendingCaps) blocks() bool {
+	return c.Mode != capModeWarn
+}
+
+// capPeriod es un período de facturación de los límites
+type capPeriod struct {
+	name  string
+	limit func(SpendingCaps) float64
+	start func(now time.Time) time.Time
+}
+
+var capPeriods = []capPeriod{
+	{
+		name:  "diario",
+		limit: func(c SpendingCaps) float64 { return c.Daily },
+		start: startOfDay,
+	},
+	{
+		name:  "semanal",
+		limit: func(c SpendingCaps) float64 { return c.Weekly },
+		start: func(now time.Time) time.Time {
+			// Las semanas empiezan el lunes
+			offset := (int(now.Weekday()) + 6) % 7
+			return startOfDay(now).AddDate(0, 0, -offset)
+		},
+	},
+	{
+		name:  "mensual",
+		limit: func(c SpendingCaps) float64 { return c.Monthly },
+		start: func(now time.Time) time.Time {
+			return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		},
+	},
+}
+
+func startOfDay(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+}
+
+// CapUsage es el gasto de un período frente a su límite
+type CapUsage struct {
+	Scope  string  `json:"scope"`
+	Period string  `json:"period"`
+	Limit  float64 `json:"limit"`
+	Spent  float64 `json:"spent"`
+	Block  bool    `json:"block"`
+}
+
+// Reached indica si el gasto alcanzó el límite
+func (u CapUsage) Reached() bool {
+	return u.Spent >= u.Limit
+}
+
+func (u CapUsage) String() string {
+	return fmt.Sprintf("Límite %s (%s) alcanzado: %.2f de %.2f CUP", u.Period, u.Scope, u.Spent, u.Limit)
+}
+
+// capUsage calcula el gasto de cada período con límite del perfil activo y
+// de la política global. El gasto global suma todos los perfiles.
+func capUsage(config *Config, now time.Time) ([]CapUsage, error) {
+	policy, err := LoadPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := loadSettledLedgerFor(currentProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	var globalRecords []SessionRecord
+	if hasCaps(policy.Caps) {
+		profiles, err := listProfiles()
+		if err != nil {
+			return nil, err
+		}
+		for _, profile := range profiles {
+			profileRecords, err := loadSettledLedgerFor(profile)
+			if err != nil {
+				return nil, err
+			}
+			globalRecords = append(globalRecords, profileRecords...)
+		}
+	}
+
+	usage := []CapUsage{}
+	for _, period := range capPeriods {
+		from := period.start(now)
+		if limit := period.limit(config.Caps); limit > 0 {
+			usage = append(usage, CapUsage{
+				Scope:  capScopeProfile,
+				Period: period.name,
+				Limit:  limit,
+				Spent:  spentBetween(records, from, now),
+				Block:  config.Caps.blocks(),
+			})
+		}
+		if limit := period.limit(policy.Caps); limit > 0 {
+			usage = append(usage, CapUsage{
+				Scope:  capScopeGlobal,
+				Period: period.name,
+				Limit:  limit,
+				Spent:  spentBetween(globalRecords, from, now),
+				Block:  policy.Caps.blocks(),
+			})
+		}
+	}
+	return usage, nil
+}
+
+func hasCaps(caps SpendingCaps) bool {
+	return caps.Daily > 0 || caps.Weekly > 0 || caps.Monthly > 0
+}
+
+// reachedCaps devuelve los límites alcanzados y si alguno impide conectar
+func reachedCaps(config *Config, now time.Time) ([]CapUsage, bool, error) {
+	usage, err := capUsage(config, now)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var reached []CapUsage
+	block := false
+	for _, u := range usage {
+		if u.Reached() {
+			reached = append(reached, u)
+			block = block || u.Block
+		}
+	}
+	return reached, block, nil
+}
+
+func capHookVars(username string, u CapUsage) map[string]string {
+	return map[string]string{
+		"USERNAME": username,
+		"SCOPE":    u.Scope,
+		"PERIOD":   u.Period,
+		"LIMIT":    fmt.Sprintf("%.2f", u.Limit),
+		"SPENT":    fmt.Sprintf("%.2f", u.Spent),
+		"MESSAGE":  u.String(),
+	}
+}
+
+// enforceCapsOnConnect avisa de los límites alcanzados y termina el programa
+// si alguno impide conectar, salvo que se fuerce la conexión. Devuelve si se
+// conecta forzando un límite que bloquea.
+func enforceCapsOnConnect(config *Config, username string, force bool) bool {
+	reached, block, err := reachedCaps(config, time.Now())
+	if err != nil {
+		fmt.Printf("Advertencia: No se pudieron comprobar los límites de gasto: %v\n", err)
+		return false
+	}
+
+	for _, u := range reached {
+		fmt.Printf("⚠️  %s\n", u)
+		fireEvent(config, eventCapReached, capHookVars(username, u))
+	}
+	if block && !force {
+		fmt.Println("No se inicia la sesión. Use 'gonauta connect --force' para conectar de todos modos")
+		os.Exit(1)
+	}
+	return block
+}
+
+// checkCaps cierra la sesión activa del perfil cuando su costo acumulado
+// alcanza un límite que bloquea, y avisa de los demás. Las sesiones abiertas
+// con --force solo se avisan: el usuario ya decidió conectar pese al límite.
+func checkCaps(d *daemon, config *Config, now time.Time) {
+	sessionData, err := LoadSession()
+	if err != nil {
+		return
+	}
+
+	reached, block, err := reachedCaps(config, now)
+	if err != nil {
+		d.logf("No se pudieron comprobar los límites de gasto: %v", err)
+		return
+	}
+	if len(reached) == 0 {
+		return
+	}
+
+	if !block {
+		d.notify(config, eventCapReached, reached[0].String(), capHookVars(sessionData.Username, reached[0]), now)
+		return
+	}
+	if sessionData.Forced {
+		d.notify(config, eventCapReached, reached[0].String()+": la sesión se abrió con --force y no se cierra", capHookVars(sessionData.Username, reached[0]), now)
+		return
+	}
+
+	// Si no se puede cerrar la sesión se reintenta en cada ciclo, pero el
+	// aviso y el error se muestran como máximo una vez cada
+	// warningRepeatInterval
+	d.notify(config, eventCapReached, reached[0].String()+": cerrando la sesión", capHookVars(sessionData.Username, reached[0]), now)
+	if _, err := closeSession(config, sessionData); err != nil && d.due("cap-close-error", warningRepeatInterval, now) {
+		d.logf("Error cerrando la sesión: %v", err)
+	}
+}
+
+func handleCaps() {
+	config, err := loadConfigOrEmpty()
+	if err != nil {
+		fmt.Printf("Error cargando configuración: %v\n", err)
+		os.Exit(1)
+	}
+
+	usage, err := capUsage(config, time.Now())
+	if err != nil {
+		fmt.Printf("Error calculando el gasto: %v\n", err)
+		os.Exit(1)
+	}
+
+	if config.jsonOutput() {
+		printJSON(usage)
+		return
+	}
+
+	if len(usage) == 0 {
+		fmt.Println("No hay límites de gasto configurados")
+		fmt.Println("Use 'gonauta config set caps.monthly <CUP>' o defina \"caps\" en ~/.gonauta/policy.json")
+		return
+	}
+
+	fmt.Printf("%-8s  %-8s  %10s  %10s  %s\n", "Ámbito", "Período", "Gastado", "Límite", "Estado")
+	for _, u := range usage {
+		state := "ok"
+		switch {
+		case u.Reached() && u.Block:
+			state = "⛔ alcanzado (bloquea)"
+		case u.Reached():
+			state = "⚠️  alcanzado"
+		}
+		fmt.Printf("%-8s  %-8s  %10.2f  %10.2f  %s\n", u.Scope, u.Period, u.Spent, u.Limit, state)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckCapsForcedSession(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("los hooks necesitan sh")
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		forced    bool
+		wantClose bool
+	}{
+		{"sesión normal", false, true},
+		{"sesión abierta con --force", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+
+			// Dos horas a 10 CUP superan el límite diario de 5 CUP
+			record := SessionRecord{ID: "actual", Username: testUsername, StartedAt: now.Add(-2 * time.Hour), HourRate: 10}
+			if err := SaveLedger([]SessionRecord{record}); err != nil {
+				t.Fatal(err)
+			}
+			session := &SessionData{Username: testUsername, UUID: redactedValue, StartedAt: record.StartedAt, RecordID: record.ID, Forced: tt.forced}
+			if err := SaveSession(session); err != nil {
+				t.Fatal(err)
+			}
+
+			// El cierre empieza por el hook pre_logout; la comprobación de la
+			// conexión que le sigue falla enseguida contra un puerto cerrado
+			marker := filepath.Join(home, "pre_logout")
+			config := &Config{
+				Caps:   SpendingCaps{Daily: 5},
+				Hooks:  HookSettings{PreLogout: "touch '" + marker + "'"},
+				Portal: PortalSettings{IPCheckURL: "http://127.0.0.1:1/"},
+				Retry:  RetrySettings{MaxAttempts: 1},
+			}
+			checkCaps(newDaemon(time.Minute), config, now)
+
+			_, err := os.Stat(marker)
+			if closed := err == nil; closed != tt.wantClose {
+				t.Errorf("checkCaps: cierre de la sesión = %v, se esperaba %v", closed, tt.wantClose)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+)
+
+// pageEncoding devuelve la codificación de una página con
+// charset.DetermineEncoding: la de la cabecera Content-Type, la de una
+// etiqueta <meta> o la que sugiere el contenido. Corrige dos casos, porque
+// DetermineEncoding solo revisa el primer KB y confía en lo declarado:
+// contenido que no es UTF-8 aunque se declare así, y UTF-8 sin declarar tras
+// un principio solo ASCII.
+func pageEncoding(body []byte, contentType string) (encoding.Encoding, string) {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+	switch {
+	case name == "utf-8" && !utf8.Valid(body):
+		return charmap.Windows1252, "windows-1252"
+	case name == "windows-1252" && !certain && utf8.Valid(body):
+		return encoding.Nop, "utf-8"
+	}
+	return enc, name
+}
+
+// decodePage convierte una página a UTF-8 y quita la marca de orden de bytes
+func decodePage(body []byte, contentType string) (string, error) {
+	enc, name := pageEncoding(body, contentType)
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return "", fmt.Errorf("decodificando la página como %s: %w", name, err)
+	}
+	return strings.TrimPrefix(string(decoded), "\uFEFF"), nil
+}
+
+// readPage lee una respuesta del portal, la convierte a UTF-8 y la analiza.
+// Devuelve también el HTML decodificado tal como llegó.
+func readPage(resp *http.Response) (*goquery.Document, string, error) {
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	body, err := decodePage(raw, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, "", err
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	return doc, body, nil
+}
+
+// jsEscapePattern encuentra los escapes \uXXXX y \xXX de las cadenas de
+// JavaScript, donde el portal pone sus mensajes de error
+var jsEscapePattern = regexp.MustCompile(`\\u[0-9a-fA-F]{4}|\\x[0-9a-fA-F]{2}`)
+
+// portalText prepara el HTML de una respuesta para buscar mensajes: decodifica
+// las entidades HTML y los escapes de JavaScript, y normaliza el texto
+func portalText(body string) string {
+	text := jsEscapePattern.ReplaceAllStringFunc(body, func(escape string) string {
+		code, err := strconv.ParseUint(escape[2:], 16, 32)
+		if err != nil {
+			return escape
+		}
+		return string(rune(code))
+	})
+	return normalizeText(html.UnescapeString(text))
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodePage(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        string
+		encoding    string
+	}{
+		{
+			name:     "Latin-1 declarado en <meta>",
+			body:     `<meta http-equiv="Content-Type" content="text/html; charset=ISO-8859-1"><p>Contrase` + "\xf1" + `a</p>`,
+			want:     `<meta http-equiv="Content-Type" content="text/html; charset=ISO-8859-1"><p>Contraseña</p>`,
+			encoding: "windows-1252",
+		},
+		{
+			name:     "<meta charset> corto",
+			body:     `<meta charset="iso-8859-15"><p>` + "\xa4" + `</p>`,
+			want:     `<meta charset="iso-8859-15"><p>€</p>`,
+			encoding: "iso-8859-15",
+		},
+		{
+			name:        "la cabecera tiene prioridad sobre <meta>",
+			body:        `<meta charset="utf-8"><p>Expiraci` + "\xf3" + `n</p>`,
+			contentType: "text/html;charset=ISO-8859-1",
+			want:        `<meta charset="utf-8"><p>Expiración</p>`,
+			encoding:    "windows-1252",
+		},
+		{
+			name:        "la cabecera declara UTF-8 pero el contenido es Latin-1",
+			body:        `<meta charset="iso-8859-1"><p>Cr` + "\xe9" + `dito</p>`,
+			contentType: "text/html; charset=utf-8",
+			want:        `<meta charset="iso-8859-1"><p>Crédito</p>`,
+			encoding:    "windows-1252",
+		},
+		{
+			name:     "Latin-1 sin declarar",
+			body:     "<p>\x93Acceso desde todas las \xe1reas\x94</p>",
+			want:     "<p>“Acceso desde todas las áreas”</p>",
+			encoding: "windows-1252",
+		},
+		{
+			name:     "UTF-8 sin declarar",
+			body:     "<p>Áreas de acceso</p>",
+			want:     "<p>Áreas de acceso</p>",
+			encoding: "utf-8",
+		},
+		{
+			name:     "UTF-8 sin declarar tras más de 1 KB en ASCII",
+			body:     strings.Repeat(" ", 2000) + "<p>Áreas</p>",
+			want:     strings.Repeat(" ", 2000) + "<p>Áreas</p>",
+			encoding: "utf-8",
+		},
+		{
+			name:     "marca de orden de bytes",
+			body:     "\xef\xbb\xbf<p>Año</p>",
+			want:     "<p>Año</p>",
+			encoding: "utf-8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, name := pageEncoding([]byte(tt.body), tt.contentType); name != tt.encoding {
+				t.Errorf("pageEncoding = %s, se esperaba %s", name, tt.encoding)
+			}
+			got, err := decodePage([]byte(tt.body), tt.contentType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("decodePage = %q, se esperaba %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortalText(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{"<td>Fecha de expiraci&oacute;n:</td>", "<td>fecha de expiracion:</td>"},
+		{"<td>Fecha de expiraci&#243;n:</td>", "<td>fecha de expiracion:</td>"},
+		{`alert("Fecha de expiraci\u00f3n")`, `alert("fecha de expiracion")`},
+		{`alert("Fecha de expiraci\u00F3n")`, `alert("fecha de expiracion")`},
+		{`alert("Fecha de expiraci\xf3n")`, `alert("fecha de expiracion")`},
+		{`alert("El nombre de usuario o contraseña son incorrectos.")`, `alert("el nombre de usuario o contrasena son incorrectos.")`},
+		{"alert(\"El usuario ya   est&aacute;\nconectado.\")", `alert("el usuario ya esta conectado.")`},
+	}
+	for _, tt := range tests {
+		if got := portalText(tt.body); got != tt.want {
+			t.Errorf("portalText(%q) = %q, se esperaba %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPortalErrorsEncodings(t *testing.T) {
+	// El mismo mensaje de error escrito como lo han enviado distintas
+	// versiones del portal
+	bodies := []string{
+		"alert(\"El nombre de usuario o contraseña son incorrectos.\")",
+		"alert(\"EL NOMBRE DE USUARIO O CONTRASE&Ntilde;A SON INCORRECTOS.\")",
+		"alert(\"El nombre de usuario o contrase&ntilde;a son incorrectos.\")",
+		`alert("El nombre de usuario o contrase\u00f1a son incorrectos.")`,
+		`alert("El nombre de usuario o contrase\xf1a son incorrectos.")`,
+	}
+	for _, body := range bodies {
+		if err := checkPortalErrors(body, testUsername); err != ErrWrongPassword {
+			t.Errorf("checkPortalErrors(%q) = %v, se esperaba %v", body, err, ErrWrongPassword)
+		}
+	}
+}
+
+func TestExtractUserInfoEncodings(t *testing.T) {
+	// Las etiquetas de la tabla en Latin-1 y con entidades HTML
+	page := "<meta charset=\"iso-8859-1\"><table id=\"sessioninfo\">" +
+		"<tr><td>Estado de la cuenta:</td><td>Activa</td></tr>" +
+		"<tr><td>Cr\xe9dito:</td><td>12,50 CUP</td></tr>" +
+		"<tr><td>Fecha de expiraci&oacute;n:</td><td>31/12/2026</td></tr>" +
+		"<tr><td>&Aacute;reas de acceso:</td><td>Acceso desde todas las \xe1reas de Internet</td></tr>" +
+		"</table>"
+	body, err := decodePage([]byte(page), "text/html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userInfo, err := extractUserInfo(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userInfo.Credits != 12.5 || userInfo.AccessInfo.Scope != AccessAll {
+		t.Errorf("extractUserInfo = %.2f CUP, %s; se esperaba 12.50 CUP, %s", userInfo.Credits, userInfo.AccessInfo.Scope, AccessAll)
+	}
+	if userInfo.ExpirationDate == nil || userInfo.ExpirationDate.Format("2006-01-02") != "2026-12-31" {
+		t.Errorf("extractUserInfo: fecha de expiración %v, se esperaba 2026-12-31", userInfo.ExpirationDate)
+	}
+}
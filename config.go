@@ -7,28 +7,169 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// ErrNoCredentials indica que el perfil no tiene configuración guardada
+var ErrNoCredentials = errors.New("no hay credenciales guardadas. Use 'gonauta login' primero")
+
+// Config es la configuración de un perfil guardada en credentials.enc. Al
+// cambiar su estructura, incremente currentConfigVersion y añada una migración
+// en migrations.go.
 type Config struct {
-	Username         string `json:"username"`
-	Password         string `json:"password"`
-	VPNConnectCmd    string `json:"vpn_connect_cmd,omitempty"`
-	VPNDisconnectCmd string `json:"vpn_disconnect_cmd,omitempty"`
+	Version     int                `json:"version"`
+	Username    string             `json:"username"`
+	Password    string             `json:"password"`
+	Credentials CredentialSettings `json:"credentials"`
+	VPN         VPNSettings        `json:"vpn"`
+	Portal      PortalSettings     `json:"portal"`
+	Thresholds  ThresholdSettings  `json:"thresholds"`
+	Hooks       HookSettings       `json:"hooks"`
+	Output      OutputSettings     `json:"output"`
+	Tariff      TariffSettings     `json:"tariff"`
+	Audit       AuditSettings      `json:"audit"`
+	Caps        SpendingCaps       `json:"caps"`
+	Schedule    ScheduleSettings   `json:"schedule"`
+	Idle        IdleSettings       `json:"idle"`
+	Traffic     TrafficSettings    `json:"traffic"`
+	AutoLogin   AutoLoginSettings  `json:"autologin"`
+	Retry       RetrySettings      `json:"retry"`
+	Proxy       ProxySettings      `json:"proxy"`
+	TLS         TLSSettings        `json:"tls"`
+	DNS         DNSSettings        `json:"dns"`
+}
+
+// CredentialSettings define de dónde se obtiene la contraseña
+type CredentialSettings struct {
+	Sources         []string `json:"sources,omitempty"`
+	PasswordCommand string   `json:"password_command,omitempty"`
+	PasswordFile    string   `json:"password_file,omitempty"`
+}
+
+// VPNSettings contiene los comandos de conexión y desconexión de la VPN
+type VPNSettings struct {
+	ConnectCmd    string `json:"connect_cmd,omitempty"`
+	DisconnectCmd string `json:"disconnect_cmd,omitempty"`
+}
+
+// PortalSettings permite cambiar los endpoints usados por el cliente
+type PortalSettings struct {
+	URL        string `json:"url,omitempty"`
+	IPCheckURL string `json:"ip_check_url,omitempty"`
+	ProbeURL   string `json:"probe_url,omitempty"`
+}
+
+// ThresholdSettings contiene los umbrales de aviso
+type ThresholdSettings struct {
+	LowBalance     float64 `json:"low_balance,omitempty"`
+	LowTimeMinutes int     `json:"low_time_minutes,omitempty"`
+	ExpiryDays     int     `json:"expiry_days,omitempty"`
+	ForecastDays   int     `json:"forecast_days,omitempty"`
+}
+
+// HookSettings contiene los comandos ejecutados en cada evento
+type HookSettings struct {
+	PostConnect string `json:"post_connect,omitempty"`
+	PreLogout   string `json:"pre_logout,omitempty"`
+	PostLogout  string `json:"post_logout,omitempty"`
+	Event       string `json:"event,omitempty"`
+}
+
+// TariffSettings permite fijar la tarifa del perfil en lugar de usar la tabla
+type TariffSettings struct {
+	HourRate    float64 `json:"hour_rate,omitempty"`
+	AccountType string  `json:"account_type,omitempty"`
+}
+
+// AuditSettings controla la auditoría de cobros de cada sesión
+type AuditSettings struct {
+	Enabled   bool    `json:"enabled,omitempty"`
+	Tolerance float64 `json:"tolerance,omitempty"`
+}
+
+// ScheduleSettings restringe las horas en que el perfil puede conectar
+type ScheduleSettings struct {
+	AllowedHours []string `json:"allowed_hours,omitempty"`
+}
+
+// IdleSettings controla el cierre de sesiones sin tráfico
+type IdleSettings struct {
+	TimeoutMinutes int     `json:"timeout_minutes,omitempty"`
+	MinRate        float64 `json:"min_rate,omitempty"`
+	Interface      string  `json:"interface,omitempty"`
+}
+
+// TrafficSettings define la interfaz cuyo tráfico se registra en el historial
+type TrafficSettings struct {
+	Interface string `json:"interface,omitempty"`
+}
+
+// AutoLoginSettings controla el inicio de sesión automático al conectarse a
+// una red de ETECSA
+type AutoLoginSettings struct {
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// RetrySettings limita los reintentos ante fallos pasajeros del portal
+type RetrySettings struct {
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	MaxSeconds  int `json:"max_seconds,omitempty"`
+}
+
+// ProxySettings define el proxy usado para acceder al portal
+type ProxySettings struct {
+	URL string `json:"url,omitempty"`
+}
+
+// TLSSettings define en qué certificados del portal se confía
+type TLSSettings struct {
+	CAFile  string   `json:"ca_file,omitempty"`
+	Pins    []string `json:"pins,omitempty"`
+	PinMode string   `json:"pin_mode,omitempty"`
+}
+
+// DNSSettings permite resolver el portal sin el DNS de la red
+type DNSSettings struct {
+	Hosts  map[string][]string `json:"hosts,omitempty"`
+	Server string              `json:"server,omitempty"`
+}
+
+// OutputSettings define el formato de salida de los comandos
+type OutputSettings struct {
+	Format string `json:"format,omitempty"`
+}
+
+// portalURL devuelve la URL del portal configurada o la de ETECSA
+func (c *Config) portalURL() string {
+	if c.Portal.URL != "" {
+		return strings.TrimRight(c.Portal.URL, "/")
+	}
+	return BaseURL
+}
+
+// ipCheckURL devuelve la URL del servicio de geolocalización configurada
+func (c *Config) ipCheckURL() string {
+	if c.Portal.IPCheckURL != "" {
+		return c.Portal.IPCheckURL
+	}
+	return IPCheckURL
+}
+
+// jsonOutput indica si los comandos deben imprimir JSON
+func (c *Config) jsonOutput() bool {
+	return c.Output.Format == outputJSON
 }
 
 func getConfigPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	profileDir, err := getProfileDir()
 	if err != nil {
 		return "", err
 	}
-	configDir := filepath.Join(homeDir, ".gonauta")
-	if err := os.MkdirAll(configDir, 0700); err != nil {
-		return "", err
-	}
-	return filepath.Join(configDir, "credentials.enc"), nil
+	return filepath.Join(profileDir, "credentials.enc"), nil
 }
 
 func getEncryptionKey() []byte {
@@ -37,7 +178,9 @@ func getEncryptionKey() []byte {
 	return key[:]
 }
 
-func encrypt(data []byte, key []byte) ([]byte, error) {
+var errTruncated = errors.New("datos cifrados inválidos")
+
+func encryptWithAAD(data []byte, key []byte, aad []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -53,10 +196,14 @@ func encrypt(data []byte, key []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	return gcm.Seal(nonce, nonce, data, nil), nil
+	return gcm.Seal(nonce, nonce, data, aad), nil
 }
 
 func decrypt(data []byte, key []byte) ([]byte, error) {
+	return decryptWithAAD(data, key, nil)
+}
+
+func decryptWithAAD(data []byte, key []byte, aad []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return nil, err
@@ -68,28 +215,24 @@ func decrypt(data []byte, key []byte) ([]byte, error) {
 	}
 
 	nonceSize := gcm.NonceSize()
-	if len(data) < nonceSize {
-		return nil, errors.New("datos cifrados inválidos")
+	if len(data) < nonceSize+gcm.Overhead() {
+		return nil, errTruncated
 	}
 
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	return gcm.Open(nil, nonce, ciphertext, nil)
+	return gcm.Open(nil, nonce, ciphertext, aad)
 }
 
-func SaveCredentials(username, password, vpnConnectCmd, vpnDisconnectCmd string) error {
-	config := Config{
-		Username:         username,
-		Password:         password,
-		VPNConnectCmd:    vpnConnectCmd,
-		VPNDisconnectCmd: vpnDisconnectCmd,
-	}
+// SaveConfig cifra y guarda la configuración del perfil activo
+func SaveConfig(config *Config) error {
+	config.Version = currentConfigVersion
 
 	data, err := json.Marshal(config)
 	if err != nil {
 		return err
 	}
 
-	encrypted, err := encrypt(data, getEncryptionKey())
+	encrypted, err := sealContainer(purposeCredentials, data, getEncryptionKey())
 	if err != nil {
 		return err
 	}
@@ -102,7 +245,8 @@ func SaveCredentials(username, password, vpnConnectCmd, vpnDisconnectCmd string)
 	return os.WriteFile(configPath, encrypted, 0600)
 }
 
-func LoadCredentials() (*Config, error) {
+// readConfigFile lee y descifra credentials.enc sin aplicar migraciones
+func readConfigFile() (*configFile, error) {
 	configPath, err := getConfigPath()
 	if err != nil {
 		return nil, err
@@ -111,24 +255,77 @@ func LoadCredentials() (*Config, error) {
 	encrypted, err := os.ReadFile(configPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, errors.New("no hay credenciales guardadas. Use 'gonauta login' primero")
+			return nil, ErrNoCredentials
 		}
 		return nil, err
 	}
 
-	decrypted, err := decrypt(encrypted, getEncryptionKey())
+	decrypted, err := openContainer(purposeCredentials, encrypted, getEncryptionKey())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", configPath, err)
+	}
+
+	var document map[string]interface{}
+	if err := json.Unmarshal(decrypted, &document); err != nil {
+		return nil, fmt.Errorf("%s: %w: contenido inválido", configPath, ErrCorruptFile)
+	}
+
+	return &configFile{
+		path:            configPath,
+		raw:             encrypted,
+		document:        document,
+		legacyContainer: isLegacyContainer(encrypted),
+	}, nil
+}
+
+// LoadConfig descifra la configuración del perfil activo. Los archivos de
+// versiones anteriores se migran en el momento, guardando una copia de
+// seguridad del original.
+func LoadConfig() (*Config, error) {
+	file, err := readConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := planConfigMigrations(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(plan) > 0 || file.legacyContainer {
+		if _, err := applyConfigMigrations(file, plan); err != nil {
+			return nil, err
+		}
+	}
+
+	return decodeConfig(file.document)
+}
+
+// decodeConfig convierte un documento JSON genérico en Config
+func decodeConfig(document map[string]interface{}) (*Config, error) {
+	data, err := json.Marshal(document)
 	if err != nil {
 		return nil, err
 	}
 
 	var config Config
-	if err := json.Unmarshal(decrypted, &config); err != nil {
+	if err := json.Unmarshal(data, &config); err != nil {
 		return nil, err
 	}
 
 	return &config, nil
 }
 
+// loadConfigOrEmpty devuelve una configuración vacía si el perfil no tiene
+// archivo guardado, para permitir el uso sin 'gonauta login'
+func loadConfigOrEmpty() (*Config, error) {
+	config, err := LoadConfig()
+	if errors.Is(err, ErrNoCredentials) {
+		return &Config{}, nil
+	}
+	return config, err
+}
+
 func DeleteCredentials() error {
 	configPath, err := getConfigPath()
 	if err != nil {
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+func printConfigUsage() {
+	fmt.Println("Uso: gonauta config <subcomando>")
+	fmt.Println("\nSubcomandos:")
+	fmt.Println("  list                - Mostrar todas las opciones y sus valores")
+	fmt.Println("  get <opción>        - Mostrar el valor de una opción")
+	fmt.Println("  set <opción> <valor> - Cambiar una opción")
+	fmt.Println("  unset <opción>      - Volver al valor por defecto")
+	fmt.Println("  edit                - Editar las opciones con $EDITOR")
+	fmt.Println("  migrate [--dry-run] - Actualizar el archivo al esquema actual")
+	fmt.Println("\nOpciones disponibles:")
+	for _, key := range configKeys {
+		fmt.Printf("  %-30s %s\n", key.name, key.description)
+	}
+}
+
+func handleConfig(args []string) {
+	if len(args) < 1 {
+		printConfigUsage()
+		os.Exit(1)
+	}
+
+	// migrate trabaja sobre el archivo sin migrar, antes de cargarlo
+	if args[0] == "migrate" {
+		handleConfigMigrate(args[1:])
+		return
+	}
+
+	config, err := loadConfigOrEmpty()
+	if err != nil {
+		fmt.Printf("Error cargando configuración: %v\n", err)
+		os.Exit(1)
+	}
+
+	subcommand, subArgs := args[0], args[1:]
+
+	switch subcommand {
+	case "list":
+		for _, key := range configKeys {
+			fmt.Printf("%-30s = %s\n", key.name, key.get(config))
+		}
+		return
+	case "get":
+		if len(subArgs) != 1 {
+			fmt.Println("Uso: gonauta config get <opción>")
+			os.Exit(1)
+		}
+		key, err := findConfigKey(subArgs[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(key.get(config))
+		return
+	case "set":
+		if len(subArgs) < 2 {
+			fmt.Println("Uso: gonauta config set <opción> <valor>")
+			os.Exit(1)
+		}
+		if err := setConfigValue(config, subArgs[0], strings.Join(subArgs[1:], " ")); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "unset":
+		if len(subArgs) != 1 {
+			fmt.Println("Uso: gonauta config unset <opción>")
+			os.Exit(1)
+		}
+		if err := setConfigValue(config, subArgs[0], ""); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "edit":
+		if err := editConfig(config); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	case "help":
+		printConfigUsage()
+		return
+	default:
+		fmt.Printf("Subcomando desconocido: %s\n\n", subcommand)
+		printConfigUsage()
+		os.Exit(1)
+	}
+
+	if err := SaveConfig(config); err != nil {
+		fmt.Printf("Error guardando configuración: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("✓ Configuración guardada")
+}
+
+// setConfigValue valida y cambia una opción
+func setConfigValue(config *Config, name, value string) error {
+	key, err := findConfigKey(name)
+	if err != nil {
+		return err
+	}
+	if err := key.set(config, strings.TrimSpace(value)); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// editConfig abre las opciones en un editor como JSON y aplica los cambios.
+// La contraseña nunca se escribe en el archivo temporal.
+func editConfig(config *Config) error {
+	values := make(map[string]string)
+	for _, key := range configKeys {
+		values[key.name] = key.get(config)
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "gonauta-config-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	editor := strings.Fields(getEditor())
+	cmd := exec.Command(editor[0], append(editor[1:], tmp.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error ejecutando el editor: %w", err)
+	}
+
+	edited, err := os.ReadFile(tmp.Name())
+	if err != nil {
+		return err
+	}
+
+	var editedValues map[string]string
+	if err := json.Unmarshal(edited, &editedValues); err != nil {
+		return fmt.Errorf("JSON inválido, no se guardaron cambios: %w", err)
+	}
+
+	updated := *config
+	for name, value := range editedValues {
+		if value == values[name] {
+			continue
+		}
+		if err := setConfigValue(&updated, name, value); err != nil {
+			return fmt.Errorf("no se guardaron cambios: %w", err)
+		}
+	}
+	*config = updated
+
+	return nil
+}
+
+// getEditor devuelve el editor configurado en el entorno
+func getEditor() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(name)); editor != "" {
+			return editor
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// handleConfigMigrate implementa 'gonauta config migrate [--dry-run]'
+func handleConfigMigrate(args []string) {
+	fs := flag.NewFlagSet("config migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "mostrar los cambios sin modificar archivos")
+	parseCommandFlags(fs, args)
+
+	file, err := readConfigFile()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	version, err := configDocumentVersion(file.document)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	plan, err := planConfigMigrations(file)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Archivo: %s\n", file.path)
+	fmt.Printf("Versión del esquema: %d (actual: %d)\n", version, currentConfigVersion)
+
+	if len(plan) == 0 && !file.legacyContainer {
+		fmt.Println("✓ La configuración ya está actualizada")
+		return
+	}
+
+	fmt.Println("\nCambios pendientes:")
+	if file.legacyContainer {
+		fmt.Println("  - Actualizar el cifrado al contenedor versionado con verificación de integridad")
+	}
+	for _, migration := range plan {
+		fmt.Printf("  - v%d: %s\n", migration.to, migration.description)
+	}
+
+	migrated, err := migrateConfigDocument(file.document, plan)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if changes := describeConfigChanges(file.document, migrated); len(changes) > 0 {
+		fmt.Println("\nDiferencias:")
+		fmt.Println("  " + strings.Join(changes, "\n  "))
+	}
+
+	if *dryRun {
+		fmt.Println("\n(--dry-run: no se ha modificado ningún archivo)")
+		return
+	}
+
+	backupPath, err := applyConfigMigrations(file, plan)
+	if err != nil {
+		fmt.Printf("Error migrando configuración: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\n✓ Configuración migrada. Copia de seguridad: %s\n", backupPath)
+}
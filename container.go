@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// Formato del contenedor cifrado usado por los archivos de ~/.gonauta:
+//
+//	magic "GNTA" (4 bytes) | versión (1 byte) | nonce | datos cifrados + tag GCM
+//
+// La cabecera y el propósito del archivo se autentican como datos adicionales
+// de GCM, de modo que un archivo no puede hacerse pasar por otro.
+const (
+	containerMagic   = "GNTA"
+	containerVersion = 1
+)
+
+const (
+	purposeCredentials = "credentials"
+	purposeSession     = "session"
+)
+
+var (
+	// ErrCorruptFile indica que un archivo cifrado está truncado, fue
+	// modificado o se cifró en otro equipo
+	ErrCorruptFile = errors.New("archivo cifrado dañado o manipulado")
+)
+
+// sealContainer cifra los datos dentro de un contenedor versionado
+func sealContainer(purpose string, data []byte, key []byte) ([]byte, error) {
+	header := append([]byte(containerMagic), containerVersion)
+
+	sealed, err := encryptWithAAD(data, key, containerAAD(header, purpose))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(header, sealed...), nil
+}
+
+// openContainer verifica y descifra un contenedor. Los archivos antiguos sin
+// cabecera se descifran con el formato original.
+func openContainer(purpose string, data []byte, key []byte) ([]byte, error) {
+	headerSize := len(containerMagic) + 1
+
+	if isLegacyContainer(data) {
+		plaintext, err := decrypt(data, key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: no se pudo verificar el contenido", ErrCorruptFile)
+		}
+		return plaintext, nil
+	}
+
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("%w: archivo truncado", ErrCorruptFile)
+	}
+
+	version := data[len(containerMagic)]
+	if version != containerVersion {
+		return nil, fmt.Errorf("versión de archivo cifrado no soportada: %d", version)
+	}
+
+	header, payload := data[:headerSize], data[headerSize:]
+	plaintext, err := decryptWithAAD(payload, key, containerAAD(header, purpose))
+	if err != nil {
+		if errors.Is(err, errTruncated) {
+			return nil, fmt.Errorf("%w: archivo truncado", ErrCorruptFile)
+		}
+		return nil, fmt.Errorf("%w: falló la verificación de integridad", ErrCorruptFile)
+	}
+
+	return plaintext, nil
+}
+
+// isLegacyContainer indica si los datos usan el formato sin cabecera anterior
+// al contenedor versionado
+func isLegacyContainer(data []byte) bool {
+	return !bytes.HasPrefix(data, []byte(containerMagic))
+}
+
+func containerAAD(header []byte, purpose string) []byte {
+	aad := append([]byte{}, header...)
+	return append(aad, purpose...)
+}
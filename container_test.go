@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestContainerRoundTrip(t *testing.T) {
+	key := getEncryptionKey()
+	plaintext := []byte(`{"username":"usuario@nauta.com.cu"}`)
+
+	sealed, err := sealContainer(purposeSession, plaintext, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(sealed, []byte{'G', 'N', 'T', 'A', containerVersion}) {
+		t.Errorf("sealContainer: cabecera %q inesperada", sealed[:5])
+	}
+
+	opened, err := openContainer(purposeSession, sealed, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened, plaintext) {
+		t.Errorf("openContainer = %q, se esperaba %q", opened, plaintext)
+	}
+
+	// Los archivos sin cabecera de versiones anteriores siguen leyéndose
+	legacy, err := encryptWithAAD(plaintext, key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := openContainer(purposeSession, legacy, key); err != nil || !bytes.Equal(opened, plaintext) {
+		t.Errorf("openContainer(formato anterior) = %q, %v; se esperaba %q", opened, err, plaintext)
+	}
+}
+
+func TestOpenContainerTampered(t *testing.T) {
+	key := getEncryptionKey()
+	sealed, err := sealContainer(purposeSession, []byte(`{"uuid":"abc"}`), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerSize := len(containerMagic) + 1
+
+	tamper := func(change func(data []byte) []byte) []byte {
+		return change(append([]byte{}, sealed...))
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		purpose string
+		corrupt bool
+		message string
+	}{
+		{
+			name:    "un byte cifrado cambiado",
+			data:    tamper(func(data []byte) []byte { data[len(data)-1] ^= 0x01; return data }),
+			corrupt: true,
+			message: "verificación de integridad",
+		},
+		{
+			name:    "truncado por debajo de la cabecera",
+			data:    sealed[:headerSize-1],
+			corrupt: true,
+			message: "truncado",
+		},
+		{
+			name:    "truncado dentro del nonce",
+			data:    sealed[:headerSize+4],
+			corrupt: true,
+			message: "truncado",
+		},
+		{
+			name:    "sin la marca del contenedor",
+			data:    tamper(func(data []byte) []byte { data[0] = 'X'; return data }),
+			corrupt: true,
+			message: "no se pudo verificar",
+		},
+		{
+			name:    "versión posterior",
+			data:    tamper(func(data []byte) []byte { data[len(containerMagic)]++; return data }),
+			message: "versión de archivo cifrado no soportada: 2",
+		},
+		{
+			name:    "otro propósito",
+			data:    sealed,
+			purpose: purposeCredentials,
+			corrupt: true,
+			message: "verificación de integridad",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purpose := tt.purpose
+			if purpose == "" {
+				purpose = purposeSession
+			}
+			_, err := openContainer(purpose, tt.data, key)
+			if err == nil {
+				t.Fatal("openContainer no devolvió error")
+			}
+			if errors.Is(err, ErrCorruptFile) != tt.corrupt {
+				t.Errorf("openContainer: %v, errors.Is(ErrCorruptFile) = %v, se esperaba %v", err, !tt.corrupt, tt.corrupt)
+			}
+			if errors.Is(err, ErrNoSession) {
+				t.Errorf("openContainer: %v no debe confundirse con %v", err, ErrNoSession)
+			}
+			if !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("openContainer: %q, se esperaba que mencionara %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestLoadSessionCorrupt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if _, err := LoadSession(); !errors.Is(err, ErrNoSession) {
+		t.Fatalf("LoadSession sin archivo: %v, se esperaba %v", err, ErrNoSession)
+	}
+
+	if err := SaveSession(&SessionData{Username: testUsername, UUID: redactedValue}); err != nil {
+		t.Fatal(err)
+	}
+	session, err := LoadSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Username != testUsername || session.UUID != redactedValue {
+		t.Errorf("LoadSession = %+v, se esperaba la sesión guardada", session)
+	}
+
+	sessionPath, err := getSessionPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(sessionPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] ^= 0x01
+	if err := os.WriteFile(sessionPath, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadSession()
+	if !errors.Is(err, ErrCorruptFile) || errors.Is(err, ErrNoSession) {
+		t.Errorf("LoadSession con el archivo modificado: %v, se esperaba %v", err, ErrCorruptFile)
+	}
+}
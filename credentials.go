@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	sourceEnv          = "env"
+	sourcePasswordFile = "password-file"
+	sourceStdin        = "stdin"
+	sourceCommand      = "command"
+	sourceFile         = "file"
+)
+
+// defaultCredentialSources es el orden usado cuando el perfil no define otro
+var defaultCredentialSources = []string{sourceEnv, sourceFile}
+
+// Credentials contiene el usuario y la contraseña resueltos
+type Credentials struct {
+	Username string
+	Password string
+	Source   string
+}
+
+// CredentialProvider obtiene credenciales de una fuente concreta. Devuelve
+// nil sin error si la fuente no tiene credenciales disponibles.
+type CredentialProvider interface {
+	Name() string
+	Credentials(config *Config) (*Credentials, error)
+}
+
+// credentialOptions contiene las fuentes indicadas en la línea de comandos,
+// que tienen prioridad sobre las configuradas en el perfil
+type credentialOptions struct {
+	passwordFile  string
+	passwordStdin bool
+}
+
+// envProvider lee GONAUTA_USERNAME y GONAUTA_PASSWORD
+type envProvider struct{}
+
+func (envProvider) Name() string { return sourceEnv }
+
+func (envProvider) Credentials(config *Config) (*Credentials, error) {
+	password, ok := os.LookupEnv("GONAUTA_PASSWORD")
+	if !ok {
+		return nil, nil
+	}
+	return &Credentials{Username: os.Getenv("GONAUTA_USERNAME"), Password: password}, nil
+}
+
+// passwordFileProvider lee la contraseña de la primera línea de un archivo
+type passwordFileProvider struct {
+	path string
+}
+
+func (passwordFileProvider) Name() string { return sourcePasswordFile }
+
+func (p passwordFileProvider) Credentials(config *Config) (*Credentials, error) {
+	path := p.path
+	if path == "" {
+		path = config.Credentials.PasswordFile
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo archivo de contraseña: %w", err)
+	}
+	defer file.Close()
+
+	password, err := readFirstLine(file)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo archivo de contraseña: %w", err)
+	}
+	return &Credentials{Password: password}, nil
+}
+
+// stdinProvider lee la contraseña de la primera línea de la entrada estándar
+type stdinProvider struct{}
+
+func (stdinProvider) Name() string { return sourceStdin }
+
+func (stdinProvider) Credentials(config *Config) (*Credentials, error) {
+	password, err := readFirstLine(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("error leyendo contraseña de la entrada estándar: %w", err)
+	}
+	return &Credentials{Password: password}, nil
+}
+
+// commandProvider ejecuta un gestor de secretos externo (ej: 'pass show nauta')
+// y usa la primera línea de su salida como contraseña
+type commandProvider struct{}
+
+func (commandProvider) Name() string { return sourceCommand }
+
+func (commandProvider) Credentials(config *Config) (*Credentials, error) {
+	if strings.TrimSpace(config.Credentials.PasswordCommand) == "" {
+		return nil, nil
+	}
+
+	var stdout bytes.Buffer
+	cmd := shellCommand(config.Credentials.PasswordCommand)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("error ejecutando '%s': %w", config.Credentials.PasswordCommand, err)
+	}
+
+	password, err := readFirstLine(&stdout)
+	if err != nil {
+		return nil, fmt.Errorf("el comando '%s' no devolvió una contraseña", config.Credentials.PasswordCommand)
+	}
+	return &Credentials{Password: password}, nil
+}
+
+// fileProvider usa la contraseña guardada en credentials.enc
+type fileProvider struct{}
+
+func (fileProvider) Name() string { return sourceFile }
+
+func (fileProvider) Credentials(config *Config) (*Credentials, error) {
+	if config.Password == "" {
+		return nil, nil
+	}
+	return &Credentials{Username: config.Username, Password: config.Password}, nil
+}
+
+// newCredentialProvider crea el proveedor correspondiente a un nombre de fuente
+func newCredentialProvider(name string) (CredentialProvider, error) {
+	switch name {
+	case sourceEnv:
+		return envProvider{}, nil
+	case sourcePasswordFile:
+		return passwordFileProvider{}, nil
+	case sourceStdin:
+		return stdinProvider{}, nil
+	case sourceCommand:
+		return commandProvider{}, nil
+	case sourceFile:
+		return fileProvider{}, nil
+	default:
+		return nil, fmt.Errorf("fuente de credenciales desconocida: %s", name)
+	}
+}
+
+// parseCredentialSources valida una lista de fuentes separadas por comas
+func parseCredentialSources(value string) ([]string, error) {
+	var sources []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, err := newCredentialProvider(name); err != nil {
+			return nil, err
+		}
+		sources = append(sources, name)
+	}
+	return sources, nil
+}
+
+// credentialChain construye la cadena de proveedores: primero las opciones de
+// la línea de comandos y después las fuentes configuradas en el perfil
+func credentialChain(config *Config, opts credentialOptions) ([]CredentialProvider, error) {
+	var chain []CredentialProvider
+	if opts.passwordFile != "" {
+		chain = append(chain, passwordFileProvider{path: opts.passwordFile})
+	}
+	if opts.passwordStdin {
+		chain = append(chain, stdinProvider{})
+	}
+
+	sources := config.Credentials.Sources
+	if len(sources) == 0 {
+		sources = defaultCredentialSources
+	}
+	for _, name := range sources {
+		provider, err := newCredentialProvider(name)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, provider)
+	}
+
+	return chain, nil
+}
+
+// resolveCredentials carga la configuración del perfil activo y recorre la
+// cadena de proveedores hasta obtener una contraseña
+func resolveCredentials(opts credentialOptions) (*Config, *Credentials, error) {
+	config, err := loadConfigOrEmpty()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	chain, err := credentialChain(config, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, provider := range chain {
+		creds, err := provider.Credentials(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		if creds == nil || creds.Password == "" {
+			continue
+		}
+
+		if creds.Username == "" {
+			creds.Username = config.Username
+		}
+		if creds.Username == "" {
+			return nil, nil, fmt.Errorf("la fuente '%s' no indica el usuario. Defina GONAUTA_USERNAME o use 'gonauta login'", provider.Name())
+		}
+		creds.Source = provider.Name()
+		return config, creds, nil
+	}
+
+	return nil, nil, ErrNoCredentials
+}
+
+// ErrInteractiveCredentials indica que el perfil necesita la entrada estándar
+// para obtener la contraseña y no puede usarse en tareas automáticas
+var ErrInteractiveCredentials = errors.New("el perfil lee la contraseña de la entrada estándar")
+
+// resolveUnattendedCredentials obtiene las credenciales del perfil activo sin
+// intervención del usuario, para el daemon y las tareas programadas
+func resolveUnattendedCredentials(config *Config) (*Credentials, error) {
+	for _, source := range config.Credentials.Sources {
+		if source == sourceStdin {
+			return nil, ErrInteractiveCredentials
+		}
+	}
+	_, creds, err := resolveCredentials(credentialOptions{})
+	return creds, err
+}
+
+// readFirstLine devuelve la primera línea de r sin el salto de línea final
+func readFirstLine(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return "", errors.New("contraseña vacía")
+	}
+	return line, nil
+}
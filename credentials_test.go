@@ -0,0 +1,32 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestCommandProviderShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("usa la sintaxis de sh")
+	}
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{`echo secreta`, "secreta"},
+		{`printf '%s\n' "con  espacios"`, "con  espacios"},
+		{`printf 'primera\nsegunda\n' | tail -n 1`, "segunda"},
+		{`PASS=de-variable; echo "$PASS"`, "de-variable"},
+	}
+	for _, tt := range tests {
+		config := &Config{Credentials: CredentialSettings{PasswordCommand: tt.command}}
+		credentials, err := commandProvider{}.Credentials(config)
+		if err != nil {
+			t.Errorf("%s: %v", tt.command, err)
+			continue
+		}
+		if credentials.Password != tt.want {
+			t.Errorf("%s = %q, se esperaba %q", tt.command, credentials.Password, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule es una expresión cron de cinco campos (minuto, hora, día del
+// mes, mes y día de la semana) evaluada en la hora local
+type cronSchedule struct {
+	expr   string
+	minute []bool
+	hour   []bool
+	dom    []bool
+	month  []bool
+	dow    []bool
+	anyDom bool
+	anyDow bool
+}
+
+// cronField describe el rango de valores de un campo
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minuto", 0, 59},
+	{"hora", 0, 23},
+	{"día del mes", 1, 31},
+	{"mes", 1, 12},
+	{"día de la semana", 0, 7},
+}
+
+// parseCron interpreta una expresión como "55 7 * * 1-5". Cada campo admite
+// '*', valores, rangos (a-b), listas (a,b) y pasos (*/n, a-b/n). En el día de
+// la semana, 0 y 7 son domingo.
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("expresión cron inválida: %q (se esperan 5 campos: minuto hora día mes día-semana)", expr)
+	}
+
+	sets := make([][]bool, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("expresión cron inválida: %q: %w", expr, err)
+		}
+		sets[i] = set
+	}
+
+	// El domingo puede escribirse como 0 o 7
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+
+	return &cronSchedule{
+		expr:   strings.Join(fields, " "),
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, spec cronField) ([]bool, error) {
+	set := make([]bool, spec.max+1)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("paso inválido en el %s: %q", spec.name, part)
+			}
+			rangePart, step = part[:i], n
+		}
+
+		low, high := spec.min, spec.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("valor inválido en el %s: %q", spec.name, part)
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("valor inválido en el %s: %q", spec.name, part)
+				}
+			} else if step > 1 {
+				high = spec.max
+			}
+		}
+		if low < spec.min || high > spec.max || low > high {
+			return nil, fmt.Errorf("el %s debe estar entre %d y %d: %q", spec.name, spec.min, spec.max, part)
+		}
+
+		for value := low; value <= high; value += step {
+			set[value] = true
+		}
+	}
+	return set, nil
+}
+
+// matches indica si la expresión se cumple en el minuto de t
+func (c *cronSchedule) matches(t time.Time) bool {
+	return c.minute[t.Minute()] && c.hour[t.Hour()] && c.month[int(t.Month())] && c.dayMatches(t)
+}
+
+// cronSearchLimit limita la búsqueda de la próxima ejecución
+const cronSearchLimit = 366 * 24 * time.Hour
+
+// next devuelve el primer minuto posterior a after en que se cumple la
+// expresión, o el instante cero si no hay ninguno en el próximo año
+func (c *cronSchedule) next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(cronSearchLimit)
+	for t.Before(limit) {
+		switch {
+		case !c.month[int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case !c.hour[t.Hour()]:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case !c.minute[t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches indica si el día de t cumple las restricciones de día del mes y
+// día de la semana. Como en cron, si se restringen ambos basta con que se
+// cumpla uno de los dos.
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom[t.Day()]
+	dowMatch := c.dow[int(t.Weekday())]
+	switch {
+	case c.anyDom && c.anyDow:
+		return true
+	case c.anyDom:
+		return dowMatch
+	case c.anyDow:
+		return domMatch
+	}
+	return domMatch || dowMatch
+}
+
+func (c *cronSchedule) String() string {
+	return c.expr
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// cronTime construye un instante en UTC para las pruebas del planificador
+func cronTime(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"1-a * * * *",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) no devolvió error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"paso en minutos", "*/15 * * * *", cronTime(2026, 10, 19, 10, 7), cronTime(2026, 10, 19, 10, 15)},
+		{"siempre posterior", "*/15 * * * *", cronTime(2026, 10, 19, 10, 15), cronTime(2026, 10, 19, 10, 30)},
+		{"lista", "5,35 * * * *", cronTime(2026, 10, 19, 10, 5), cronTime(2026, 10, 19, 10, 35)},
+		{"rango con paso", "0 8-18/4 * * *", cronTime(2026, 10, 19, 10, 0), cronTime(2026, 10, 19, 12, 0)},
+		{"rango con paso al día siguiente", "0 8-18/4 * * *", cronTime(2026, 10, 19, 16, 0), cronTime(2026, 10, 20, 8, 0)},
+		{"valor con paso hasta el máximo", "0 20/2 * * *", cronTime(2026, 10, 19, 21, 0), cronTime(2026, 10, 19, 22, 0)},
+		{"días laborables desde el viernes", "55 7 * * 1-5", cronTime(2026, 10, 16, 8, 0), cronTime(2026, 10, 19, 7, 55)},
+		{"domingo como 7", "0 8 * * 7", cronTime(2026, 10, 19, 0, 0), cronTime(2026, 10, 25, 8, 0)},
+		{"domingo como 0", "0 8 * * 0", cronTime(2026, 10, 19, 0, 0), cronTime(2026, 10, 25, 8, 0)},
+		{"día del mes o de la semana: el día 13", "0 9 13 * 5", cronTime(2026, 10, 10, 0, 0), cronTime(2026, 10, 13, 9, 0)},
+		{"día del mes o de la semana: el viernes", "0 9 13 * 5", cronTime(2026, 10, 13, 10, 0), cronTime(2026, 10, 16, 9, 0)},
+		{"día del mes y mes", "0 0 1 1,7 *", cronTime(2026, 2, 1, 0, 0), cronTime(2026, 7, 1, 0, 0)},
+		{"fin de mes", "0 0 * * *", cronTime(2026, 2, 28, 23, 0), cronTime(2026, 3, 1, 0, 0)},
+		{"día 31 salta los meses de 30 días", "30 23 31 * *", cronTime(2026, 10, 31, 23, 30), cronTime(2026, 12, 31, 23, 30)},
+		{"fin de año", "0 0 1 * *", cronTime(2026, 12, 31, 23, 59), cronTime(2027, 1, 1, 0, 0)},
+		{"29 de febrero en el próximo año", "0 12 29 2 *", cronTime(2027, 3, 1, 0, 0), cronTime(2028, 2, 29, 12, 0)},
+		{"sin ejecución en el próximo año", "0 12 29 2 *", cronTime(2026, 3, 1, 0, 0), time.Time{}},
+		{"segundos ignorados", "* * * * *", cronTime(2026, 10, 19, 10, 7).Add(30 * time.Second), cronTime(2026, 10, 19, 10, 8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := schedule.next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("next(%s) con %q = %s, se esperaba %s", tt.after, tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	tests := []struct {
+		expr string
+		at   time.Time
+		want bool
+	}{
+		{"* 7-21 * * *", cronTime(2026, 10, 19, 7, 0), true},
+		{"* 7-21 * * *", cronTime(2026, 10, 19, 21, 59), true},
+		{"* 7-21 * * *", cronTime(2026, 10, 19, 22, 0), false},
+		// Con ambos días restringidos basta con uno: el 13 es martes
+		{"* * 13 * 5", cronTime(2026, 10, 13, 12, 0), true},
+		{"* * 13 * 5", cronTime(2026, 10, 16, 12, 0), true},
+		{"* * 13 * 5", cronTime(2026, 10, 14, 12, 0), false},
+		// Con uno solo restringido debe cumplirse ese
+		{"* * 13 * *", cronTime(2026, 10, 16, 12, 0), false},
+		{"* * * * 5", cronTime(2026, 10, 13, 12, 0), false},
+	}
+	for _, tt := range tests {
+		schedule, err := parseCron(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := schedule.matches(tt.at); got != tt.want {
+			t.Errorf("matches(%s) con %q = %v, se esperaba %v", tt.at, tt.expr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultDaemonInterval = time.Minute
+
+	// accountRefreshInterval es cada cuánto el daemon vuelve a consultar el
+	// saldo y la expiración de cada perfil
+	accountRefreshInterval = 12 * time.Hour
+
+	// warningRepeatInterval evita repetir el mismo aviso en cada ciclo
+	warningRepeatInterval = 24 * time.Hour
+)
+
+// daemonCheck es una comprobación que el daemon ejecuta en cada ciclo para
+// cada perfil. El perfil ya está activo cuando se llama.
+type daemonCheck func(d *daemon, config *Config, now time.Time)
+
+// daemonChecks son las comprobaciones ejecutadas en cada ciclo, en orden
+var daemonChecks = []daemonCheck{
+	checkNetwork,
+	checkAccountRefresh,
+	checkExpiry,
+	checkForecast,
+	checkCaps,
+	checkSchedule,
+	checkIdle,
+	checkTraffic,
+}
+
+// daemon mantiene el estado entre ciclos de 'gonauta daemon'
+type daemon struct {
+	interval     time.Duration
+	lastNotified map[string]time.Time
+	lastAttempt  map[string]time.Time
+	idle         map[string]*idleState
+
+	// network es el estado de la red detectado al comienzo del ciclo y
+	// portalTicks los ciclos seguidos en que el portal pidió iniciar sesión
+	network     NetworkState
+	portalTicks int
+}
+
+func newDaemon(interval time.Duration) *daemon {
+	return &daemon{
+		interval:     interval,
+		lastNotified: make(map[string]time.Time),
+		lastAttempt:  make(map[string]time.Time),
+		idle:         make(map[string]*idleState),
+	}
+}
+
+// logf escribe un mensaje con la hora y el perfil activo
+func (d *daemon) logf(format string, args ...interface{}) {
+	fmt.Printf("%s [%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), currentProfile, fmt.Sprintf(format, args...))
+}
+
+// notify muestra un aviso y lo envía a los hooks, como máximo una vez cada
+// warningRepeatInterval para la misma clave
+func (d *daemon) notify(config *Config, event, message string, vars map[string]string, now time.Time) {
+	key := currentProfile + "/" + event
+	if last, ok := d.lastNotified[key]; ok && now.Sub(last) < warningRepeatInterval {
+		return
+	}
+	d.lastNotified[key] = now
+
+	d.logf("⚠️  %s", message)
+	fireEvent(config, event, vars)
+}
+
+// due indica si una tarea periódica de un perfil debe ejecutarse y registra
+// el intento
+func (d *daemon) due(task string, every time.Duration, now time.Time) bool {
+	key := currentProfile + "/" + task
+	if last, ok := d.lastAttempt[key]; ok && now.Sub(last) < every {
+		return false
+	}
+	d.lastAttempt[key] = now
+	return true
+}
+
+// observeNetwork detecta el estado de la red una vez por ciclo y registra los
+// cambios
+func (d *daemon) observeNetwork(config *Config) {
+	state, err := detectNetworkState(config)
+	if state != d.network {
+		if err != nil {
+			d.logf("Estado de la red: %s (%v)", state, err)
+		} else {
+			d.logf("Estado de la red: %s", state)
+		}
+	}
+	d.network = state
+	if state == NetworkPortal {
+		d.portalTicks++
+	} else {
+		d.portalTicks = 0
+	}
+}
+
+// tick ejecuta todas las comprobaciones para cada perfil guardado
+func (d *daemon) tick(now time.Time) {
+	// La detección usa la configuración del perfil por defecto
+	withProfile(defaultProfile, func() {
+		config, err := loadConfigOrEmpty()
+		if err != nil {
+			config = &Config{}
+		}
+		d.observeNetwork(config)
+	})
+
+	profiles, err := listProfiles()
+	if err != nil {
+		d.logf("Error listando perfiles: %v", err)
+		return
+	}
+
+	for _, profile := range profiles {
+		withProfile(profile, func() {
+			config, err := LoadConfig()
+			if err != nil {
+				d.logf("Error cargando configuración: %v", err)
+				return
+			}
+			for _, check := range daemonChecks {
+				check(d, config, now)
+			}
+		})
+	}
+}
+
+// run ejecuta ciclos hasta que se cancele el contexto. Si el perfil por
+// defecto tiene activado el inicio automático, también vigila los cambios de
+// red e intenta iniciar sesión tras cada uno.
+func (d *daemon) run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	changes := make(chan struct{}, 1)
+	if autoLoginEnabled() {
+		go func() {
+			// No se usa d.logf porque el perfil activo cambia en cada ciclo
+			if err := watchNetworkChanges(ctx, changes); err != nil {
+				fmt.Printf("%s No se pueden vigilar los cambios de red: %v\n", time.Now().Format("2006-01-02 15:04:05"), err)
+			}
+		}()
+	}
+
+	// Los cambios de red llegan en ráfagas; se espera a que se asienten
+	settle := time.NewTimer(networkSettleDelay)
+	settle.Stop()
+
+	d.tick(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.tick(now)
+		case <-changes:
+			settle.Reset(networkSettleDelay)
+		case <-settle.C:
+			tryAutoLogin(d.logf)
+		}
+	}
+}
+
+// checkAccountRefresh actualiza el saldo y la expiración conocidos del perfil
+// si hay credenciales disponibles sin intervención del usuario
+func checkAccountRefresh(d *daemon, config *Config, now time.Time) {
+	if d.network == NetworkOffline {
+		return
+	}
+	state, _ := LoadAccountStateFor(currentProfile)
+	if state != nil && now.Sub(state.UpdatedAt) < accountRefreshInterval {
+		return
+	}
+	if !d.due("refresh", accountRefreshInterval, now) {
+		return
+	}
+	creds, err := resolveUnattendedCredentials(config)
+	if err != nil {
+		return
+	}
+	client, err := NewClient(config)
+	if err != nil {
+		return
+	}
+	userInfo, err := client.GetUserInfo(creds.Username, creds.Password)
+	if err != nil {
+		d.logf("No se pudo actualizar el estado de la cuenta: %v", err)
+		return
+	}
+	if err := rememberAccountState(creds.Username, userInfo); err != nil {
+		d.logf("No se pudo guardar el estado de la cuenta: %v", err)
+	}
+}
+
+// checkExpiry avisa cuando la cuenta del perfil está por expirar
+func checkExpiry(d *daemon, config *Config, now time.Time) {
+	state, err := LoadAccountStateFor(currentProfile)
+	if err != nil || state == nil {
+		return
+	}
+
+	message, ok := state.expiryWarning(config.expiryDays(), now)
+	if !ok {
+		return
+	}
+	d.notify(config, eventExpiryWarning, message, expiryHookVars(state, message), now)
+}
+
+func handleDaemon(args []string) {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	interval := fs.Duration("interval", defaultDaemonInterval, "intervalo entre comprobaciones")
+	parseCommandFlags(fs, args)
+
+	if *interval < time.Second {
+		fmt.Println("Error: El intervalo debe ser de al menos 1s")
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("GoNauta daemon iniciado (intervalo: %s). Pulse Ctrl+C para detenerlo\n", *interval)
+	newDaemon(*interval).run(ctx)
+	fmt.Println("GoNauta daemon detenido")
+}
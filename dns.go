@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// dnsPort es el puerto de dns.server si no se indica otro
+	dnsPort = "53"
+
+	// dnsTimeout limita cada consulta al servidor DNS configurado
+	dnsTimeout = 5 * time.Second
+
+	// dialTimeout y dialKeepAlive son los valores de http.DefaultTransport
+	dialTimeout   = 30 * time.Second
+	dialKeepAlive = 30 * time.Second
+)
+
+// parseHostOverrides lee entradas host=ip separadas por comas. Repetir un host
+// le asigna varias direcciones, que se prueban en orden.
+func parseHostOverrides(value string) (map[string][]string, error) {
+	hosts := make(map[string][]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		host, ip, ok := strings.Cut(entry, "=")
+		host, ip = strings.ToLower(strings.TrimSpace(host)), strings.TrimSpace(ip)
+		if !ok || host == "" || strings.ContainsAny(host, " /:") {
+			return nil, fmt.Errorf("entrada inválida: %s (use host=ip)", entry)
+		}
+		if net.ParseIP(ip) == nil {
+			return nil, fmt.Errorf("dirección IP inválida para %s: %s", host, ip)
+		}
+		hosts[host] = append(hosts[host], ip)
+	}
+	if len(hosts) == 0 {
+		return nil, nil
+	}
+	return hosts, nil
+}
+
+// formatHostOverrides escribe las entradas en el formato de parseHostOverrides
+func formatHostOverrides(hosts map[string][]string) string {
+	names := make([]string, 0, len(hosts))
+	for host := range hosts {
+		names = append(names, host)
+	}
+	sort.Strings(names)
+
+	var entries []string
+	for _, host := range names {
+		for _, ip := range hosts[host] {
+			entries = append(entries, host+"="+ip)
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+// parseDNSServer valida un servidor DNS ip[:puerto] y devuelve su dirección
+// con el puerto
+func parseDNSServer(value string) (string, error) {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		// Sin puerto (también las IPv6 sin corchetes)
+		host, port = strings.Trim(value, "[]"), dnsPort
+	}
+	if net.ParseIP(host) == nil {
+		return "", fmt.Errorf("servidor DNS inválido: %s (use una IP, con puerto opcional)", value)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// dialContext devuelve la función de conexión del transporte: usa las
+// direcciones de dns.hosts y resuelve el resto de nombres con dns.server. Sin
+// ninguno de los dos devuelve nil y se usa la resolución del sistema. La URL
+// conserva el nombre, así que TLS se sigue verificando contra él.
+func (c *Config) dialContext() (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	if len(c.DNS.Hosts) == 0 && c.DNS.Server == "" {
+		return nil, nil
+	}
+
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: dialKeepAlive}
+	if c.DNS.Server != "" {
+		server, err := parseDNSServer(c.DNS.Server)
+		if err != nil {
+			return nil, err
+		}
+		dialer.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				resolverDialer := net.Dialer{Timeout: dnsTimeout}
+				return resolverDialer.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	hosts := c.DNS.Hosts
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		ips := hosts[strings.ToLower(host)]
+		if len(ips) == 0 {
+			return dialer.DialContext(ctx, network, addr)
+		}
+
+		verbosef("%s resuelto con dns.hosts: %s", host, strings.Join(ips, ", "))
+		var firstErr error
+		for _, ip := range ips {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		return nil, firstErr
+	}, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+const (
+	eventForecastWarning = "forecast_warning"
+
+	// defaultForecastDays es la antelación del aviso de agotamiento del saldo
+	// si el perfil no define thresholds.forecast_days
+	defaultForecastDays = 3
+
+	// forecastHistoryDays es el periodo de historial usado por defecto para
+	// calcular el gasto diario
+	forecastHistoryDays = 30
+
+	// topUpHorizonDays es el periodo que debe cubrir la recarga recomendada
+	topUpHorizonDays = 30
+)
+
+// Forecast es la estimación de cuándo se agotará el saldo de un perfil según
+// su historial de sesiones
+type Forecast struct {
+	Profile    string     `json:"profile"`
+	Username   string     `json:"username"`
+	Credits    float64    `json:"credits"`
+	DailySpend float64    `json:"daily_spend"`
+	Depletion  *time.Time `json:"depletion,omitempty"`
+	Expiration *time.Time `json:"expiration,omitempty"`
+	TopUp      float64    `json:"top_up"`
+}
+
+// forecastDays devuelve con cuántos días de antelación avisar del agotamiento
+func (c *Config) forecastDays() int {
+	if c.Thresholds.ForecastDays > 0 {
+		return c.Thresholds.ForecastDays
+	}
+	return defaultForecastDays
+}
+
+// dailySpend calcula el gasto medio por día en los últimos days días. Si el
+// historial es más corto, se promedia desde la primera sesión.
+func dailySpend(records []SessionRecord, days int, now time.Time) float64 {
+	since := now.AddDate(0, 0, -days)
+	first := now
+	total := 0.0
+	for i := range records {
+		record := &records[i]
+		if record.StartedAt.Before(since) {
+			continue
+		}
+		if record.StartedAt.Before(first) {
+			first = record.StartedAt
+		}
+		total += record.RunningCost(now)
+	}
+	if total == 0 {
+		return 0
+	}
+
+	elapsed := math.Max(now.Sub(first).Hours()/24, 1)
+	return total / elapsed
+}
+
+// forecastProfile estima el agotamiento del saldo del perfil activo. Devuelve
+// nil si nunca se ha consultado el saldo.
+func forecastProfile(historyDays, horizonDays int, now time.Time) (*Forecast, error) {
+	state, err := LoadAccountStateFor(currentProfile)
+	if err != nil || state == nil {
+		return nil, err
+	}
+	records, err := loadSettledLedgerFor(currentProfile)
+	if err != nil {
+		return nil, err
+	}
+
+	credits := math.Max(state.Credits-spentBetween(records, state.UpdatedAt, now), 0)
+	forecast := &Forecast{
+		Profile:    currentProfile,
+		Username:   state.Username,
+		Credits:    credits,
+		DailySpend: dailySpend(records, historyDays, now),
+		Expiration: state.ExpirationDate,
+	}
+
+	if forecast.DailySpend > 0 {
+		depletion := now.Add(time.Duration(credits / forecast.DailySpend * 24 * float64(time.Hour)))
+		forecast.Depletion = &depletion
+		forecast.TopUp = math.Max(math.Ceil(forecast.DailySpend*float64(horizonDays)-credits), 0)
+	}
+	return forecast, nil
+}
+
+// warning devuelve un aviso si el saldo se agota dentro de days días
+func (f *Forecast) warning(days int, now time.Time) (string, bool) {
+	if f == nil || f.Depletion == nil {
+		return "", false
+	}
+	left := daysUntil(*f.Depletion, now)
+	if left >= days {
+		return "", false
+	}
+	if f.Expiration != nil && f.Expiration.Before(*f.Depletion) {
+		return "", false
+	}
+	return fmt.Sprintf("Al ritmo actual (%.2f CUP/día), el saldo de %s (%.2f CUP) se agota el %s",
+		f.DailySpend, f.Username, f.Credits, f.Depletion.Format("02/01/2006")), true
+}
+
+func forecastHookVars(f *Forecast, message string) map[string]string {
+	return map[string]string{
+		"USERNAME":    f.Username,
+		"CREDITS":     fmt.Sprintf("%.2f", f.Credits),
+		"DAILY_SPEND": fmt.Sprintf("%.2f", f.DailySpend),
+		"DEPLETION":   f.Depletion.Format("2006-01-02"),
+		"TOP_UP":      fmt.Sprintf("%.2f", f.TopUp),
+		"MESSAGE":     message,
+	}
+}
+
+// checkForecast avisa cuando el saldo del perfil se agota pronto
+func checkForecast(d *daemon, config *Config, now time.Time) {
+	forecast, err := forecastProfile(forecastHistoryDays, topUpHorizonDays, now)
+	if err != nil || forecast == nil {
+		return
+	}
+
+	message, ok := forecast.warning(config.forecastDays(), now)
+	if !ok {
+		return
+	}
+	d.notify(config, eventForecastWarning, message, forecastHookVars(forecast, message), now)
+}
+
+func handleForecast(args []string) {
+	fs := flag.NewFlagSet("forecast", flag.ContinueOnError)
+	days := fs.Int("days", forecastHistoryDays, "días de historial usados para calcular el gasto diario")
+	horizon := fs.Int("horizon", topUpHorizonDays, "días que debe cubrir la recarga recomendada")
+	parseCommandFlags(fs, args)
+
+	if *days < 1 || *horizon < 1 {
+		fmt.Println("Error: --days y --horizon deben ser al menos 1")
+		os.Exit(1)
+	}
+
+	config, err := loadConfigOrEmpty()
+	if err != nil {
+		fmt.Printf("Error cargando configuración: %v\n", err)
+		os.Exit(1)
+	}
+
+	profiles, err := listProfiles()
+	if err != nil {
+		fmt.Printf("Error listando perfiles: %v\n", err)
+		os.Exit(1)
+	}
+
+	now := time.Now()
+	forecasts := []*Forecast{}
+	for _, profile := range profiles {
+		withProfile(profile, func() {
+			forecast, err := forecastProfile(*days, *horizon, now)
+			if err != nil {
+				fmt.Printf("%s: Error calculando el pronóstico: %v\n", profile, err)
+				return
+			}
+			if forecast != nil {
+				forecasts = append(forecasts, forecast)
+			}
+		})
+	}
+
+	if config.jsonOutput() {
+		printJSON(forecasts)
+		return
+	}
+
+	if len(forecasts) == 0 {
+		fmt.Println("No hay saldo conocido para ningún perfil")
+		fmt.Println("El estado de cada cuenta se actualiza con 'gonauta info' o con el daemon")
+		return
+	}
+
+	fmt.Printf("%-12s  %-28s  %10s  %9s  %-10s  %8s\n", "Perfil", "Usuario", "Saldo", "CUP/día", "Se agota", "Recarga")
+	for _, forecast := range forecasts {
+		depletion := "-"
+		if forecast.Depletion != nil {
+			depletion = forecast.Depletion.Format("02/01/2006")
+		}
+		fmt.Printf("%-12s  %-28s  %10.2f  %9.2f  %-10s  %8.2f\n",
+			forecast.Profile, forecast.Username, forecast.Credits, forecast.DailySpend, depletion, forecast.TopUp)
+		if forecast.Depletion != nil && forecast.Expiration != nil && forecast.Expiration.Before(*forecast.Depletion) {
+			fmt.Printf("  ⚠️  La cuenta expira el %s, antes de agotar el saldo\n", formatExpirationDate(forecast.Expiration))
+		}
+	}
+	fmt.Printf("\nGasto calculado con los últimos %d días; la recarga recomendada cubre %d días\n", *days, *horizon)
+}
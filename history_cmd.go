@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// exportCSV es el formato CSV de 'history --export'
+const exportCSV = "csv"
+
+// findSessionRecord busca una sesión en el historial del perfil activo
+func findSessionRecord(id string) *SessionRecord {
+	if id == "" {
+		return nil
+	}
+	records, err := LoadLedger()
+	if err != nil {
+		return nil
+	}
+	for i := range records {
+		if records[i].ID == id {
+			return &records[i]
+		}
+	}
+	return nil
+}
+
+func handleHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	limit := fs.Int("limit", 20, "número máximo de sesiones a mostrar (0 para todas)")
+	export := fs.String("export", "", "exportar las sesiones en formato csv o json")
+	parseCommandFlags(fs, args)
+
+	if *export != "" && *export != exportCSV && *export != outputJSON {
+		fmt.Printf("Error: formato de exportación inválido: %s (use csv o json)\n", *export)
+		os.Exit(1)
+	}
+
+	config, err := loadConfigOrEmpty()
+	if err != nil {
+		fmt.Printf("Error cargando configuración: %v\n", err)
+		os.Exit(1)
+	}
+
+	records, err := LoadLedger()
+	if err != nil {
+		fmt.Printf("Error leyendo historial: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *limit > 0 && len(records) > *limit {
+		records = records[len(records)-*limit:]
+	}
+
+	switch {
+	case *export == exportCSV:
+		if err := exportHistoryCSV(os.Stdout, records, time.Now()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exportando historial: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	case *export == outputJSON || config.jsonOutput():
+		printJSON(records)
+		return
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No hay sesiones registradas")
+		return
+	}
+
+	now := time.Now()
+	total := 0.0
+	var totalTraffic uint64
+	fmt.Printf("%-16s  %-16s  %-8s  %8s  %10s  %10s\n", "ID", "Inicio", "Duración", "CUP/h", "Costo", "Tráfico")
+	for i := range records {
+		record := &records[i]
+		duration := formatDuration(record.Duration(now))
+		if record.EndedAt == nil {
+			duration += " (abierta)"
+		}
+		cost := record.RunningCost(now)
+		total += cost
+		traffic := "-"
+		if record.Traffic != nil {
+			traffic = formatBytes(record.Traffic.Total())
+			totalTraffic += record.Traffic.Total()
+		}
+		fmt.Printf("%-16s  %-16s  %-8s  %8.2f  %10.2f  %10s\n",
+			record.ID, record.StartedAt.Format("2006-01-02 15:04"), duration, record.HourRate, cost, traffic)
+	}
+	fmt.Printf("\nTotal: %.2f CUP y %s en %d sesiones\n", total, formatBytes(totalTraffic), len(records))
+}
+
+// exportHistoryCSV escribe las sesiones en formato CSV
+func exportHistoryCSV(w io.Writer, records []SessionRecord, now time.Time) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"id", "username", "started_at", "ended_at", "duration_seconds",
+		"tariff", "hour_rate", "cost", "interface", "rx_bytes", "tx_bytes"})
+	for i := range records {
+		record := &records[i]
+		endedAt := ""
+		if record.EndedAt != nil {
+			endedAt = record.EndedAt.Format(time.RFC3339)
+		}
+		iface, rx, tx := "", "", ""
+		if record.Traffic != nil {
+			iface = record.Traffic.Interface
+			rx = strconv.FormatUint(record.Traffic.RxBytes, 10)
+			tx = strconv.FormatUint(record.Traffic.TxBytes, 10)
+		}
+		writer.Write([]string{
+			record.ID,
+			record.Username,
+			record.StartedAt.Format(time.RFC3339),
+			endedAt,
+			strconv.Itoa(int(record.Duration(now).Seconds())),
+			record.Tariff,
+			strconv.FormatFloat(record.HourRate, 'f', 2, 64),
+			strconv.FormatFloat(record.RunningCost(now), 'f', 2, 64),
+			iface,
+			rx,
+			tx,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func handleTariffs() {
+	config, err := loadConfigOrEmpty()
+	if err != nil {
+		fmt.Printf("Error cargando configuración: %v\n", err)
+		os.Exit(1)
+	}
+
+	tariffs, err := LoadTariffs()
+	if err != nil {
+		fmt.Printf("Error cargando tarifas: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("%-20s  %-14s  %-14s  %8s  %s\n", "Nombre", "Dominio", "Tipo", "CUP/h", "Vigencia")
+	for _, tariff := range tariffs {
+		validity := "siempre"
+		if tariff.EffectiveFrom != "" || tariff.EffectiveUntil != "" {
+			validity = fmt.Sprintf("%s → %s", tariff.EffectiveFrom, tariff.EffectiveUntil)
+		}
+		fmt.Printf("%-20s  %-14s  %-14s  %8.2f  %s\n",
+			tariff.Name, tariff.Domain, tariff.AccountType, tariff.HourRate, validity)
+	}
+
+	if config.Username == "" {
+		return
+	}
+	tariff, err := resolveTariff(config, config.Username, time.Now())
+	if err != nil {
+		fmt.Printf("\n⚠️  %v\n", err)
+		return
+	}
+	fmt.Printf("\nTarifa actual del perfil %s: %s (%.2f CUP/h)\n", currentProfile, tariff.Name, tariff.HourRate)
+}
+
+func handleExpiring(args []string) {
+	fs := flag.NewFlagSet("expiring", flag.ContinueOnError)
+	days := fs.Int("days", 0, "días de antelación (por defecto, thresholds.expiry_days de cada perfil)")
+	all := fs.Bool("all", false, "mostrar todos los perfiles con fecha de expiración")
+	parseCommandFlags(fs, args)
+
+	profiles, err := listProfiles()
+	if err != nil {
+		fmt.Printf("Error listando perfiles: %v\n", err)
+		os.Exit(1)
+	}
+
+	now := time.Now()
+	found := 0
+	totalLost := 0.0
+	for _, profile := range profiles {
+		withProfile(profile, func() {
+			config, err := LoadConfig()
+			if err != nil {
+				fmt.Printf("%s: Error cargando configuración: %v\n", profile, err)
+				return
+			}
+			state, err := LoadAccountStateFor(profile)
+			if err != nil {
+				fmt.Printf("%s: Error leyendo estado de la cuenta: %v\n", profile, err)
+				return
+			}
+			if state == nil || state.ExpirationDate == nil {
+				return
+			}
+
+			window := config.expiryDays()
+			if *days > 0 {
+				window = *days
+			}
+			_, expiring := state.expiryWarning(window, now)
+			if !expiring && !*all {
+				return
+			}
+
+			if found == 0 {
+				fmt.Printf("%-12s  %-28s  %-10s  %6s  %10s  %s\n", "Perfil", "Usuario", "Expira", "Días", "Saldo", "Actualizado")
+			}
+			found++
+			if expiring {
+				totalLost += state.Credits
+			}
+			fmt.Printf("%-12s  %-28s  %-10s  %6d  %10.2f  %s\n",
+				profile, state.Username, formatExpirationDate(state.ExpirationDate),
+				daysUntil(*state.ExpirationDate, now), state.Credits, state.UpdatedAt.Format("2006-01-02"))
+		})
+	}
+
+	if found == 0 {
+		fmt.Println("Ningún perfil expira pronto")
+		fmt.Println("El estado de cada cuenta se actualiza con 'gonauta info' o con el daemon")
+		return
+	}
+	fmt.Printf("\nSaldo que se perdería: %.2f CUP\n", totalLost)
+}
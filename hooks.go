@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Eventos notificados a los hooks
+const (
+	eventPostConnect = "post_connect"
+	eventPreLogout   = "pre_logout"
+	eventPostLogout  = "post_logout"
+	eventLowBalance  = "low_balance"
+	eventLowTime     = "low_time"
+)
+
+// runHook ejecuta un hook con la información del evento en variables de
+// entorno GONAUTA_*. Los fallos se muestran como advertencia.
+func runHook(command, event string, vars map[string]string) {
+	if strings.TrimSpace(command) == "" {
+		return
+	}
+
+	cmd := shellCommand(command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"GONAUTA_EVENT="+event,
+		"GONAUTA_PROFILE="+currentProfile,
+	)
+	for key, value := range vars {
+		cmd.Env = append(cmd.Env, "GONAUTA_"+key+"="+value)
+	}
+
+	if err := cmd.Run(); err != nil {
+		fmt.Printf("⚠️  Error ejecutando hook %s: %v\n", event, err)
+	}
+}
+
+// fireEvent ejecuta el hook específico del evento (si existe) y el hook
+// general de eventos
+func fireEvent(config *Config, event string, vars map[string]string) {
+	switch event {
+	case eventPostConnect:
+		runHook(config.Hooks.PostConnect, event, vars)
+	case eventPreLogout:
+		runHook(config.Hooks.PreLogout, event, vars)
+	case eventPostLogout:
+		runHook(config.Hooks.PostLogout, event, vars)
+	}
+	runHook(config.Hooks.Event, event, vars)
+}
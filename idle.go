@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	eventIdleLogout = "idle_logout"
+
+	// defaultIdleMinRate es el tráfico en KB/s por debajo del cual la sesión
+	// se considera inactiva si el perfil no define idle.min_rate
+	defaultIdleMinRate = 1.0
+)
+
+// idleMinRate devuelve el umbral de inactividad en bytes por segundo
+func (c *Config) idleMinRate() float64 {
+	if c.Idle.MinRate > 0 {
+		return c.Idle.MinRate * 1024
+	}
+	return defaultIdleMinRate * 1024
+}
+
+// idleState es el seguimiento del tráfico de la sesión de un perfil
+type idleState struct {
+	recordID  string
+	iface     string
+	last      InterfaceCounters
+	lastAt    time.Time
+	idleSince time.Time
+}
+
+// observe registra una lectura de los contadores y devuelve el tráfico medio
+// en bytes por segundo desde la anterior. Si no hay lectura anterior válida,
+// reinicia el seguimiento y devuelve false.
+func (s *idleState) observe(counters InterfaceCounters, now time.Time) (float64, bool) {
+	delta, ok := counters.Sub(s.last)
+	elapsed := now.Sub(s.lastAt).Seconds()
+	s.last, s.lastAt = counters, now
+	if !ok || elapsed <= 0 {
+		s.idleSince = now
+		return 0, false
+	}
+	return float64(delta.Total()) / elapsed, true
+}
+
+// checkIdle cierra la sesión del perfil cuando el tráfico se mantiene por
+// debajo de idle.min_rate durante idle.timeout_minutes
+func checkIdle(d *daemon, config *Config, now time.Time) {
+	if config.Idle.TimeoutMinutes <= 0 {
+		return
+	}
+
+	sessionData, err := LoadSession()
+	if err != nil {
+		delete(d.idle, currentProfile)
+		return
+	}
+
+	iface, counters, err := selectInterface(config.Idle.Interface, sessionData.VPN)
+	if err != nil {
+		if d.due("idle-error", warningRepeatInterval, now) {
+			d.logf("No se puede vigilar la inactividad: %v", err)
+		}
+		return
+	}
+
+	state := d.idle[currentProfile]
+	if state == nil || state.recordID != sessionData.RecordID || state.iface != iface {
+		d.idle[currentProfile] = &idleState{
+			recordID:  sessionData.RecordID,
+			iface:     iface,
+			last:      counters,
+			lastAt:    now,
+			idleSince: now,
+		}
+		return
+	}
+
+	rate, ok := state.observe(counters, now)
+	if !ok {
+		return
+	}
+	if rate >= config.idleMinRate() {
+		state.idleSince = now
+		return
+	}
+
+	timeout := time.Duration(config.Idle.TimeoutMinutes) * time.Minute
+	if now.Sub(state.idleSince) < timeout {
+		return
+	}
+
+	message := fmt.Sprintf("Sin tráfico en %s durante %d minutos: cerrando la sesión", iface, config.Idle.TimeoutMinutes)
+	d.logf("⚠️  %s", message)
+	delete(d.idle, currentProfile)
+	if _, err := closeSession(config, sessionData); err != nil {
+		d.logf("Error cerrando la sesión: %v", err)
+		return
+	}
+	fireEvent(config, eventIdleLogout, map[string]string{
+		"USERNAME":  sessionData.Username,
+		"INTERFACE": iface,
+		"MESSAGE":   message,
+	})
+}
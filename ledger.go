@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SessionRecord es una sesión registrada en el historial del perfil
+type SessionRecord struct {
+	ID         string     `json:"id"`
+	Username   string     `json:"username"`
+	StartedAt  time.Time  `json:"started_at"`
+	EndedAt    *time.Time `json:"ended_at,omitempty"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+	Tariff     string     `json:"tariff"`
+	HourRate   float64    `json:"hour_rate"`
+	Cost       float64    `json:"cost"`
+
+	Audit   *SessionAudit   `json:"audit,omitempty"`
+	Traffic *SessionTraffic `json:"traffic,omitempty"`
+}
+
+// Duration devuelve la duración de la sesión; si sigue abierta, hasta now
+func (r *SessionRecord) Duration(now time.Time) time.Duration {
+	end := now
+	if r.EndedAt != nil {
+		end = *r.EndedAt
+	}
+	return end.Sub(r.StartedAt)
+}
+
+// RunningCost devuelve el costo de la sesión; si sigue abierta, hasta now
+func (r *SessionRecord) RunningCost(now time.Time) float64 {
+	if r.EndedAt != nil {
+		return r.Cost
+	}
+	return r.Duration(now).Hours() * r.HourRate
+}
+
+// lastActivity devuelve la última vez que se supo que la sesión seguía
+// abierta, o su inicio si nunca se comprobó
+func (r *SessionRecord) lastActivity() time.Time {
+	if r.LastSeenAt != nil {
+		return *r.LastSeenAt
+	}
+	return r.StartedAt
+}
+
+// settleDanglingRecords cierra las sesiones abiertas distintas de activeID.
+// Son sesiones que terminaron fuera de gonauta (el portal las cerró sin que
+// hubiera red, o se perdió la sesión guardada) y, si no, acumularían costo
+// para siempre. Se cierran en su última actividad conocida.
+func settleDanglingRecords(records []SessionRecord, activeID string) bool {
+	changed := false
+	for i := range records {
+		record := &records[i]
+		if record.EndedAt != nil || (activeID != "" && record.ID == activeID) {
+			continue
+		}
+		endedAt := record.lastActivity()
+		record.EndedAt = &endedAt
+		record.Cost = record.Duration(endedAt).Hours() * record.HourRate
+		changed = true
+	}
+	return changed
+}
+
+// spentBetween devuelve el costo de la parte de cada sesión comprendida entre
+// from y to. Las sesiones abiertas cuentan hasta to.
+func spentBetween(records []SessionRecord, from, to time.Time) float64 {
+	total := 0.0
+	for i := range records {
+		record := &records[i]
+		start := record.StartedAt
+		if start.Before(from) {
+			start = from
+		}
+		end := to
+		if record.EndedAt != nil && record.EndedAt.Before(to) {
+			end = *record.EndedAt
+		}
+		if end.After(start) {
+			total += end.Sub(start).Hours() * record.HourRate
+		}
+	}
+	return total
+}
+
+func getLedgerPathFor(profile string) (string, error) {
+	profileDir, err := getProfileDirFor(profile)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(profileDir, "history.json"), nil
+}
+
+// LoadLedgerFor devuelve el historial de sesiones de un perfil
+func LoadLedgerFor(profile string) ([]SessionRecord, error) {
+	ledgerPath, err := getLedgerPathFor(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(ledgerPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var records []SessionRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("%s: %w", ledgerPath, err)
+	}
+	return records, nil
+}
+
+// LoadLedger devuelve el historial de sesiones del perfil activo
+func LoadLedger() ([]SessionRecord, error) {
+	return LoadLedgerFor(currentProfile)
+}
+
+// loadSettledLedgerFor devuelve el historial de un perfil con las sesiones
+// abandonadas ya cerradas (ver settleDanglingRecords), sin guardarlo. Es el
+// que se usa para calcular gastos.
+func loadSettledLedgerFor(profile string) ([]SessionRecord, error) {
+	records, err := LoadLedgerFor(profile)
+	if err != nil {
+		return nil, err
+	}
+	activeID := ""
+	withProfile(profile, func() {
+		if sessionData, err := LoadSession(); err == nil {
+			activeID = sessionData.RecordID
+		}
+	})
+	settleDanglingRecords(records, activeID)
+	return records, nil
+}
+
+// SaveLedger guarda el historial de sesiones del perfil activo
+func SaveLedger(records []SessionRecord) error {
+	ledgerPath, err := getLedgerPathFor(currentProfile)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(ledgerPath, data, 0600)
+}
+
+// recordSessionStart añade una sesión abierta al historial y devuelve su ID.
+// Las sesiones que seguían abiertas se cierran: solo puede haber una.
+func recordSessionStart(username string, startedAt time.Time, tariff Tariff) (string, error) {
+	records, err := LoadLedger()
+	if err != nil {
+		return "", err
+	}
+	settleDanglingRecords(records, "")
+
+	record := SessionRecord{
+		ID:        startedAt.Format("20060102-150405"),
+		Username:  username,
+		StartedAt: startedAt,
+		Tariff:    tariff.Name,
+		HourRate:  tariff.HourRate,
+	}
+	records = append(records, record)
+
+	return record.ID, SaveLedger(records)
+}
+
+// updateSessionRecord aplica update a una sesión del historial y la guarda
+func updateSessionRecord(id string, update func(*SessionRecord)) (*SessionRecord, error) {
+	records, err := LoadLedger()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		if records[i].ID != id {
+			continue
+		}
+		update(&records[i])
+		if err := SaveLedger(records); err != nil {
+			return nil, err
+		}
+		return &records[i], nil
+	}
+
+	return nil, fmt.Errorf("la sesión %s no está en el historial", id)
+}
+
+// markSessionSeen registra que la sesión seguía abierta en el portal
+func markSessionSeen(id string, now time.Time) {
+	if id == "" {
+		return
+	}
+	updateSessionRecord(id, func(record *SessionRecord) {
+		if record.EndedAt == nil {
+			record.LastSeenAt = &now
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrVPNWithoutDisconnect indica que la sesión no puede cerrarse porque la
+// conexión sale por una VPN y no hay comando para desconectarla
+var ErrVPNWithoutDisconnect = errors.New("conectado a través de VPN sin comando de desconexión configurado")
+
+// connectOptions ajusta el inicio de una sesión
+type connectOptions struct {
+	audit   bool
+	skipVPN bool
+	// forced indica que se conecta con --force pese a un límite de gasto que
+	// bloquea; el daemon no cierra esa sesión al alcanzarlo
+	forced bool
+}
+
+// openSession inicia sesión en el portal con el perfil activo, la registra en
+// el historial, la guarda y ejecuta la VPN y los hooks de conexión. La usan
+// 'connect' y las tareas automáticas.
+func openSession(config *Config, creds *Credentials, opts connectOptions) (*SessionData, error) {
+	client, err := NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("creando cliente: %w", err)
+	}
+
+	// No intentar iniciar sesión si no hay red o si no hace falta
+	state, err := detectNetworkState(config)
+	switch state {
+	case NetworkOffline:
+		return nil, err
+	case NetworkOnline:
+		return nil, ErrAlreadyOnline
+	case NetworkUnknown:
+		if config.probeURL() != probeDisabled {
+			fmt.Printf("Advertencia: No se pudo determinar el estado de la red: %v\n", err)
+		}
+	}
+
+	var audit *SessionAudit
+	if auditEnabled(config, opts.audit) {
+		fmt.Println("Auditoría: consultando saldo inicial...")
+		audit = &SessionAudit{CreditsBefore: measureCredits(config, creds)}
+	}
+
+	fmt.Println("Conectando a Nauta...")
+	var session *SessionData
+	if state == NetworkPortal || errors.Is(err, ErrProbeUnreachable) {
+		// Sin acceso a internet no puede haber una VPN activa, y la
+		// comprobación de la IP fallaría igual que la detección
+		session, err = client.loginPortal(creds.Username, creds.Password)
+	} else {
+		session, err = client.Login(creds.Username, creds.Password)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session.StartedAt = time.Now()
+	session.Forced = opts.forced
+	if tariff, err := resolveTariff(config, session.Username, session.StartedAt); err != nil {
+		fmt.Printf("Advertencia: %v\n", err)
+	} else if id, err := recordSessionStart(session.Username, session.StartedAt, tariff); err != nil {
+		fmt.Printf("Advertencia: No se pudo registrar la sesión en el historial: %v\n", err)
+	} else {
+		session.RecordID = id
+		snapshotTraffic(config, id)
+	}
+
+	if err := SaveSession(session); err != nil {
+		fmt.Printf("Advertencia: No se pudo guardar la sesión: %v\n", err)
+	}
+
+	fmt.Println("✓ Sesión iniciada exitosamente")
+	fmt.Printf("  Usuario: %s\n", session.Username)
+
+	if audit != nil && session.RecordID != "" {
+		audit.RemainingAtStart = measureRemainingTime(NewSession(*session, client))
+		if _, err := updateSessionRecord(session.RecordID, func(record *SessionRecord) { record.Audit = audit }); err != nil {
+			fmt.Printf("Advertencia: No se pudo guardar la auditoría: %v\n", err)
+		}
+	}
+
+	// Ejecutar comando de conexión VPN si está configurado
+	if config.VPN.ConnectCmd != "" && !opts.skipVPN {
+		fmt.Println("\nConectando VPN...")
+		if err := executeCommand(config.VPN.ConnectCmd); err != nil {
+			fmt.Printf("⚠️  Error ejecutando comando VPN: %v\n", err)
+		} else {
+			fmt.Println("✓ VPN conectado")
+			session.VPN = true
+			if err := SaveSession(session); err != nil {
+				fmt.Printf("Advertencia: No se pudo guardar la sesión: %v\n", err)
+			}
+		}
+	}
+
+	fireEvent(config, eventPostConnect, map[string]string{"USERNAME": session.Username})
+	return session, nil
+}
+
+// printSessionInUseHint explica qué hacer cuando el inicio de sesión pudo
+// dejar una sesión abierta en el portal sin que gonauta conozca su UUID
+func printSessionInUseHint(err error) {
+	switch {
+	case errors.Is(err, ErrLoginUncertain):
+		fmt.Println("Si al volver a conectar el portal indica que la cuenta está siendo usada, la sesión quedó abierta:")
+		fmt.Println("ciérrela desde el portal de ETECSA o espere a que termine")
+	case errors.Is(err, ErrAccountInUse):
+		fmt.Println("Si un intento anterior no se confirmó, la sesión puede estar abierta en este equipo:")
+		fmt.Println("ciérrela desde el portal de ETECSA o espere a que termine")
+	}
+}
+
+// endStaleSession da por terminada una sesión que el portal ya cerró, por
+// ejemplo al agotarse el saldo: borra la sesión guardada y cierra su registro
+// en el historial con la hora actual
+func endStaleSession(config *Config, sessionData *SessionData) {
+	if err := DeleteSession(); err != nil {
+		fmt.Printf("Advertencia: No se pudo eliminar el archivo de sesión: %v\n", err)
+	}
+
+	existing := findSessionRecord(sessionData.RecordID)
+	if existing == nil || existing.EndedAt != nil {
+		return
+	}
+	endedAt := time.Now()
+	traffic, _ := measureTraffic(config, existing, endedAt)
+	_, err := updateSessionRecord(sessionData.RecordID, func(record *SessionRecord) {
+		record.EndedAt = &endedAt
+		record.Cost = record.Duration(endedAt).Hours() * record.HourRate
+		if traffic != nil {
+			record.Traffic = traffic
+		}
+	})
+	if err != nil {
+		fmt.Printf("Advertencia: No se pudo actualizar el historial: %v\n", err)
+	}
+}
+
+// closeSession desconecta la VPN si hace falta, cierra la sesión en el portal,
+// borra la sesión guardada y cierra su registro en el historial. Devuelve el
+// registro cerrado, o nil si la sesión no estaba en el historial.
+func closeSession(config *Config, sessionData *SessionData) (*SessionRecord, error) {
+	client, err := NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("creando cliente: %w", err)
+	}
+
+	hookVars := map[string]string{"USERNAME": sessionData.Username}
+	fireEvent(config, eventPreLogout, hookVars)
+
+	// Verificar si está conectado a través de VPN
+	fmt.Println("Verificando conexión...")
+	ipInfo, err := client.checkConnection()
+	if err != nil {
+		return nil, fmt.Errorf("verificando conexión: %w", err)
+	}
+
+	// Si está conectado desde fuera de Cuba (VPN detectado)
+	if ipInfo.CountryCode != "CU" {
+		if config.VPN.DisconnectCmd == "" {
+			fmt.Printf("\n⚠️  Conectado a través de VPN\n")
+			fmt.Printf("País: %s\n", ipInfo.Country)
+			fmt.Printf("ISP: %s\n\n", ipInfo.ISP)
+			return nil, ErrVPNWithoutDisconnect
+		}
+
+		fmt.Printf("\n⚠️  Conectado a través de VPN (País: %s, ISP: %s)\n", ipInfo.Country, ipInfo.ISP)
+		fmt.Println("Desconectando VPN...")
+		time.Sleep(2 * time.Second) // Delay de 2 segundos
+		if err := executeCommand(config.VPN.DisconnectCmd); err != nil {
+			fmt.Printf("⚠️  Error ejecutando comando VPN: %v\n", err)
+		} else {
+			fmt.Println("✓ VPN desconectado")
+		}
+	}
+
+	session := NewSession(*sessionData, client)
+
+	// Medir el tiempo restante antes de cerrar si la sesión se audita
+	existing := findSessionRecord(sessionData.RecordID)
+	var audit *SessionAudit
+	if existing != nil && existing.Audit != nil {
+		audit = existing.Audit
+		audit.RemainingAtEnd = measureRemainingTime(session)
+	}
+
+	fmt.Println("Cerrando sesión...")
+	if err := session.Logout(); err != nil {
+		return nil, err
+	}
+
+	if err := DeleteSession(); err != nil {
+		fmt.Printf("Advertencia: No se pudo eliminar el archivo de sesión: %v\n", err)
+	}
+
+	fmt.Println("✓ Sesión cerrada exitosamente")
+
+	var record *SessionRecord
+	if existing != nil {
+		endedAt := time.Now()
+		traffic, _ := measureTraffic(config, existing, endedAt)
+		if audit != nil {
+			if creds, err := resolveUnattendedCredentials(config); err == nil {
+				fmt.Println("Auditoría: consultando saldo final...")
+				audit.CreditsAfter = measureCredits(config, creds)
+			}
+		}
+
+		record, err = updateSessionRecord(sessionData.RecordID, func(record *SessionRecord) {
+			record.EndedAt = &endedAt
+			record.Cost = record.Duration(endedAt).Hours() * record.HourRate
+			if audit != nil {
+				record.Audit = audit
+			}
+			if traffic != nil {
+				record.Traffic = traffic
+			}
+		})
+		if err != nil {
+			fmt.Printf("Advertencia: No se pudo actualizar el historial: %v\n", err)
+		} else {
+			fmt.Printf("  Duración: %s\n", formatDuration(record.Duration(*record.EndedAt)))
+			fmt.Printf("  Costo estimado: %.2f CUP\n", record.Cost)
+			if record.Traffic != nil {
+				fmt.Printf("  Tráfico: ↓ %s  ↑ %s\n", formatBytes(record.Traffic.RxBytes), formatBytes(record.Traffic.TxBytes))
+			}
+			if audit != nil {
+				reportAuditDiscrepancies(config, record)
+			}
+		}
+	}
+
+	fireEvent(config, eventPostLogout, hookVars)
+	return record, nil
+}
@@ -2,9 +2,13 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
+	"runtime"
 	"strings"
 	"syscall"
 	"time"
@@ -13,24 +17,73 @@ import (
 )
 
 func main() {
-	if len(os.Args) < 2 {
+	globalFlags := flag.NewFlagSet("gonauta", flag.ContinueOnError)
+	globalFlags.Usage = printUsage
+	profile := globalFlags.String("profile", os.Getenv("GONAUTA_PROFILE"), "perfil a usar")
+	globalFlags.BoolVar(&verbose, "verbose", false, "mostrar detalles como los reintentos")
+	tracePath := globalFlags.String("trace", "", "guardar las peticiones HTTP en un archivo HAR")
+	traceUnredacted := globalFlags.Bool("trace-unredacted", false, "no ocultar contraseñas, UUID ni cookies en la traza")
+	if err := globalFlags.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+
+	if *tracePath != "" {
+		tracer = newHARRecorder(*tracePath, !*traceUnredacted)
+		verbosef("Guardando las peticiones HTTP en %s", *tracePath)
+	}
+
+	if err := setProfile(*profile); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	args := globalFlags.Args()
+	if len(args) < 1 {
 		printUsage()
 		return
 	}
 
-	command := os.Args[1]
+	command, cmdArgs := args[0], args[1:]
 
 	switch command {
 	case "login":
-		handleLogin()
+		handleLogin(cmdArgs)
 	case "connect":
-		handleConnect()
+		handleConnect(cmdArgs)
 	case "logout":
 		handleLogout()
 	case "status":
 		handleStatus()
 	case "info":
-		handleInfo()
+		handleInfo(cmdArgs)
+	case "profiles":
+		handleProfiles()
+	case "config":
+		handleConfig(cmdArgs)
+	case "history":
+		handleHistory(cmdArgs)
+	case "tariffs":
+		handleTariffs()
+	case "expiring":
+		handleExpiring(cmdArgs)
+	case "forecast":
+		handleForecast(cmdArgs)
+	case "run":
+		handleRun(cmdArgs)
+	case "schedule":
+		handleSchedule(cmdArgs)
+	case "caps":
+		handleCaps()
+	case "audit":
+		handleAudit(cmdArgs)
+	case "replay":
+		handleReplay(cmdArgs)
+	case "autologin":
+		handleAutoLogin(cmdArgs)
+	case "tls":
+		handleTLS(cmdArgs)
+	case "daemon":
+		handleDaemon(cmdArgs)
 	case "help":
 		printUsage()
 	default:
@@ -40,30 +93,117 @@ func main() {
 	}
 }
 
+// verbose activa los mensajes de detalle (--verbose)
+var verbose bool
+
+// verbosef muestra un mensaje de detalle si se usó --verbose
+func verbosef(format string, args ...interface{}) {
+	if verbose {
+		fmt.Printf("  · "+format+"\n", args...)
+	}
+}
+
+// parseCommandFlags analiza las opciones de un subcomando y termina el
+// programa si son inválidas
+func parseCommandFlags(fs *flag.FlagSet, args []string) {
+	if err := fs.Parse(args); err != nil {
+		os.Exit(2)
+	}
+}
+
+// addCredentialFlags registra las opciones comunes para indicar la contraseña
+func addCredentialFlags(fs *flag.FlagSet) *credentialOptions {
+	opts := &credentialOptions{}
+	fs.StringVar(&opts.passwordFile, "password-file", "", "leer la contraseña de un archivo")
+	fs.BoolVar(&opts.passwordStdin, "password-stdin", false, "leer la contraseña de la entrada estándar")
+	return opts
+}
+
+// printJSON imprime un valor como JSON indentado
+func printJSON(v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		fmt.Printf("Error generando JSON: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
+}
+
+// formatDuration formatea una duración como HH:MM:SS
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
 // executeCommand ejecuta un comando del sistema
 func executeCommand(cmdString string) error {
-	parts := strings.Fields(cmdString)
-	if len(parts) == 0 {
+	if strings.TrimSpace(cmdString) == "" {
 		return fmt.Errorf("comando vacío")
 	}
 
-	cmd := exec.Command(parts[0], parts[1:]...)
+	cmd := shellCommand(cmdString)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
+// shellCommand prepara un comando configurado por el usuario para ejecutarlo
+// con el intérprete del sistema, que respeta comillas, variables y tuberías
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
 func printUsage() {
 	fmt.Println("GoNauta - Cliente CLI para Nauta")
-	fmt.Println("\nUso: gonauta <comando> [opciones]")
+	fmt.Println("\nUso: gonauta [--profile <nombre>] [--verbose] [--trace <archivo.har>] <comando> [opciones]")
 	fmt.Println("\nComandos disponibles:")
-	fmt.Println("  login [--vpn] - Guardar credenciales (usuario y contraseña)")
+	fmt.Println("  login [--vpn] - Verificar y guardar credenciales (usuario y contraseña)")
 	fmt.Println("                  --vpn: Configurar comandos de VPN")
+	fmt.Println("                  --no-verify: Guardar sin verificar la cuenta en el portal")
+	fmt.Println("                  --username, --password-file, --password-stdin: Uso no interactivo")
+	fmt.Println("                  --password-command: Obtener la contraseña de un gestor de secretos")
+	fmt.Println("                  --sources: Orden de las fuentes de credenciales del perfil")
 	fmt.Println("  connect       - Iniciar sesión en Nauta (ejecuta VPN automáticamente si está configurado)")
+	fmt.Println("                  --audit: Medir saldo y tiempo para auditar el cobro de la sesión")
+	fmt.Println("                  --force: Conectar aunque se haya alcanzado un límite de gasto")
+	fmt.Println("  run -- <cmd>  - Conectar, ejecutar un comando y cerrar la sesión al terminar")
+	fmt.Println("                  --max-duration: Detener el comando y cerrar la sesión tras esta duración")
+	fmt.Println("                  --no-vpn: No ejecutar el comando de conexión VPN")
 	fmt.Println("  logout        - Cerrar sesión activa (desconecta VPN automáticamente si está configurado)")
 	fmt.Println("  status        - Ver tiempo restante de la sesión activa")
 	fmt.Println("  info          - Ver información completa del usuario")
+	fmt.Println("  profiles      - Listar los perfiles guardados")
+	fmt.Println("  config        - Ver y editar la configuración sin volver a escribir la contraseña")
+	fmt.Println("                  list, get, set, unset, edit (use 'gonauta config help')")
+	fmt.Println("  history       - Ver el historial de sesiones y su costo")
+	fmt.Println("  tariffs       - Ver la tabla de tarifas y la que aplica al perfil")
+	fmt.Println("  expiring      - Ver qué perfiles expiran pronto y el saldo que se perdería")
+	fmt.Println("  forecast      - Estimar cuándo se agota el saldo de cada perfil y cuánto recargar")
+	fmt.Println("  schedule      - Programar conexiones y desconexiones (list, add, remove)")
+	fmt.Println("  caps          - Ver el gasto del día, la semana y el mes frente a los límites")
+	fmt.Println("  audit [id]    - Comparar lo cobrado por el portal con la duración de las sesiones")
+	fmt.Println("  replay <har>  - Ejecutar el cliente con las respuestas grabadas con --trace")
+	fmt.Println("  autologin     - Iniciar sesión al conectarse a una red de ETECSA")
+	fmt.Println("                  run, install-dispatcher, remove-dispatcher")
+	fmt.Println("  tls           - Revisar y fijar el certificado del portal (show, pin)")
+	fmt.Println("  daemon        - Vigilar los perfiles en segundo plano y emitir avisos")
 	fmt.Println("  help          - Mostrar esta ayuda")
+	fmt.Println("\nPerfiles:")
+	fmt.Println("  Use --profile <nombre> o la variable GONAUTA_PROFILE para trabajar con varias cuentas.")
+	fmt.Println("  Use --verbose para ver los detalles, como los reintentos ante fallos del portal.")
+	fmt.Println("\nDiagnóstico:")
+	fmt.Println("  --trace <archivo.har> guarda todas las peticiones HTTP para adjuntarlas a un reporte de error.")
+	fmt.Println("  Las contraseñas, los UUID de sesión y las cookies se ocultan salvo con --trace-unredacted.")
+	fmt.Println("\nFuentes de credenciales (en orden de prioridad):")
+	fmt.Println("  --password-file / --password-stdin en connect e info")
+	fmt.Println("  env:           GONAUTA_USERNAME y GONAUTA_PASSWORD")
+	fmt.Println("  command:       Salida de --password-command (ej: pass show nauta)")
+	fmt.Println("  password-file: Archivo configurado en el perfil")
+	fmt.Println("  stdin:         Primera línea de la entrada estándar")
+	fmt.Println("  file:          Credenciales cifradas guardadas con 'gonauta login' (por defecto: env,file)")
 	fmt.Println("\nConfiguración de VPN:")
 	fmt.Println("  Los comandos VPN se ejecutan automáticamente:")
 	fmt.Println("  - Conexión VPN: Después de conectar a Nauta")
@@ -77,66 +217,168 @@ func printUsage() {
 	fmt.Println("    Desconexión: nordvpn disconnect")
 }
 
-func handleLogin() {
+func handleLogin(args []string) {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	configureVPN := fs.Bool("vpn", false, "configurar comandos de VPN")
+	usernameFlag := fs.String("username", os.Getenv("GONAUTA_USERNAME"), "usuario de Nauta")
+	passwordCommand := fs.String("password-command", "", "comando que imprime la contraseña")
+	sources := fs.String("sources", "", "fuentes de credenciales separadas por comas")
+	noVerify := fs.Bool("no-verify", false, "guardar sin verificar la cuenta en el portal")
+	opts := addCredentialFlags(fs)
+	parseCommandFlags(fs, args)
+
 	reader := bufio.NewReader(os.Stdin)
 
-	// Verificar si se pasó el flag --vpn
-	configureVPN := false
-	if len(os.Args) > 2 && os.Args[2] == "--vpn" {
-		configureVPN = true
+	config, err := loadConfigOrEmpty()
+	if err != nil {
+		fmt.Printf("Error cargando configuración: %v\n", err)
+		os.Exit(1)
 	}
 
-	fmt.Print("Usuario (ej: usuario@nauta.com.cu): ")
-	username, _ := reader.ReadString('\n')
-	username = strings.TrimSpace(username)
+	username := strings.TrimSpace(*usernameFlag)
+	if username == "" {
+		if opts.passwordStdin {
+			fmt.Println("Error: Con --password-stdin indique el usuario con --username o GONAUTA_USERNAME")
+			os.Exit(1)
+		}
+		fmt.Print("Usuario (ej: usuario@nauta.com.cu): ")
+		username, _ = reader.ReadString('\n')
+		username = strings.TrimSpace(username)
+	}
 
 	if username == "" {
 		fmt.Println("Error: El usuario no puede estar vacío")
 		os.Exit(1)
 	}
 
-	fmt.Print("Contraseña: ")
-	passwordBytes, err := term.ReadPassword(int(syscall.Stdin))
-	fmt.Println()
-	if err != nil {
-		fmt.Printf("Error leyendo contraseña: %v\n", err)
-		os.Exit(1)
+	var password string
+	switch {
+	case *passwordCommand != "":
+		// La contraseña queda en el gestor de secretos y no se guarda
+	case opts.passwordFile != "" || opts.passwordStdin:
+		var provider CredentialProvider = stdinProvider{}
+		if opts.passwordFile != "" {
+			provider = passwordFileProvider{path: opts.passwordFile}
+		}
+		creds, err := provider.Credentials(config)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		password = creds.Password
+	default:
+		fmt.Print("Contraseña: ")
+		passwordBytes, err := term.ReadPassword(int(syscall.Stdin))
+		fmt.Println()
+		if err != nil {
+			fmt.Printf("Error leyendo contraseña: %v\n", err)
+			os.Exit(1)
+		}
+
+		password = string(passwordBytes)
+		if password == "" {
+			fmt.Println("Error: La contraseña no puede estar vacía")
+			os.Exit(1)
+		}
 	}
 
-	password := string(passwordBytes)
-	if password == "" {
-		fmt.Println("Error: La contraseña no puede estar vacía")
-		os.Exit(1)
+	config.Username = username
+	config.Password = password
+	config.Credentials.PasswordCommand = *passwordCommand
+	// Sin --password-command la contraseña se guarda en el archivo, así que se
+	// vuelve al orden por defecto aunque un login anterior lo cambiara
+	config.Credentials.Sources = nil
+	if *passwordCommand != "" {
+		config.Credentials.Sources = []string{sourceEnv, sourceCommand}
+	}
+	if *sources != "" {
+		parsed, err := parseCredentialSources(*sources)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		config.Credentials.Sources = parsed
 	}
 
-	var vpnConnectCmd, vpnDisconnectCmd string
+	if !*noVerify {
+		if err := verifyLogin(config); err != nil {
+			fmt.Printf("Error verificando credenciales: %v\n", err)
+			fmt.Println("Las credenciales no se han guardado. Use --no-verify para guardarlas sin conexión")
+			os.Exit(1)
+		}
+	}
 
-	if configureVPN {
+	if *configureVPN {
 		fmt.Println("\n--- Configuración de VPN ---")
 		fmt.Print("Comando de conexión VPN: ")
-		vpnConnectCmd, _ = reader.ReadString('\n')
-		vpnConnectCmd = strings.TrimSpace(vpnConnectCmd)
+		vpnConnectCmd, _ := reader.ReadString('\n')
+		config.VPN.ConnectCmd = strings.TrimSpace(vpnConnectCmd)
 
 		fmt.Print("Comando de desconexión VPN: ")
-		vpnDisconnectCmd, _ = reader.ReadString('\n')
-		vpnDisconnectCmd = strings.TrimSpace(vpnDisconnectCmd)
+		vpnDisconnectCmd, _ := reader.ReadString('\n')
+		config.VPN.DisconnectCmd = strings.TrimSpace(vpnDisconnectCmd)
 	}
 
-	if err := SaveCredentials(username, password, vpnConnectCmd, vpnDisconnectCmd); err != nil {
+	if err := SaveConfig(config); err != nil {
 		fmt.Printf("Error guardando credenciales: %v\n", err)
 		os.Exit(1)
 	}
 
 	fmt.Println("✓ Credenciales guardadas exitosamente")
-	if configureVPN && (vpnConnectCmd != "" || vpnDisconnectCmd != "") {
+	if currentProfile != defaultProfile {
+		fmt.Printf("  Perfil: %s\n", currentProfile)
+	}
+	if *configureVPN && (config.VPN.ConnectCmd != "" || config.VPN.DisconnectCmd != "") {
 		fmt.Println("✓ Configuración de VPN guardada")
 	}
 	fmt.Println("  Use 'gonauta connect' para iniciar sesión")
 }
 
-func handleConnect() {
+// verifyLogin consulta la cuenta en el portal con las credenciales indicadas
+// y muestra su estado
+func verifyLogin(config *Config) error {
+	password := config.Password
+	if config.Credentials.PasswordCommand != "" {
+		creds, err := commandProvider{}.Credentials(config)
+		if err != nil {
+			return err
+		}
+		password = creds.Password
+	}
+
+	client, err := NewClient(config)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Verificando cuenta en el portal...")
+	userInfo, err := client.GetUserInfo(config.Username, password)
+	if err != nil {
+		return err
+	}
+	if err := rememberAccountState(config.Username, userInfo); err != nil {
+		fmt.Printf("Advertencia: No se pudo guardar el estado de la cuenta: %v\n", err)
+	}
+
+	fmt.Println("✓ Cuenta verificada")
+	fmt.Printf("  Estado: %s\n", userInfo.Status)
+	fmt.Printf("  Créditos: %.2f CUP\n", userInfo.Credits)
+	fmt.Printf("  Fecha de expiración: %s%s\n", userInfo.expiration(), userInfo.expirationNote())
+	return nil
+}
+
+func handleConnect(args []string) {
+	fs := flag.NewFlagSet("connect", flag.ContinueOnError)
+	opts := addCredentialFlags(fs)
+	auditFlag := fs.Bool("audit", false, "auditar el cobro de esta sesión (ver 'gonauta audit')")
+	force := fs.Bool("force", false, "conectar aunque se haya alcanzado un límite de gasto")
+	parseCommandFlags(fs, args)
+
 	// Verificar si ya existe una sesión activa
 	existingSession, err := LoadSession()
+	if errors.Is(err, ErrCorruptFile) {
+		fmt.Printf("⚠️  Se ignora el archivo de sesión: %v\n", err)
+	}
 	if err == nil && existingSession != nil {
 		fmt.Println("⚠️  Ya existe una sesión activa")
 		fmt.Printf("  Usuario: %s\n", existingSession.Username)
@@ -145,42 +387,28 @@ func handleConnect() {
 		os.Exit(0)
 	}
 
-	config, err := LoadCredentials()
+	config, creds, err := resolveCredentials(*opts)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		fmt.Println("Use 'gonauta login' para guardar sus credenciales primero")
 		os.Exit(1)
 	}
 
-	client, err := NewClient()
-	if err != nil {
-		fmt.Printf("Error creando cliente: %v\n", err)
+	if err := checkAllowedHours(config, time.Now()); err != nil {
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
+	forced := enforceCapsOnConnect(config, creds.Username, *force)
 
-	fmt.Println("Conectando a Nauta...")
-	session, err := client.Login(config.Username, config.Password)
-	if err != nil {
+	if _, err := openSession(config, creds, connectOptions{audit: *auditFlag, forced: forced}); err != nil {
 		fmt.Printf("Error al iniciar sesión: %v\n", err)
-		os.Exit(1)
-	}
-
-	if err := SaveSession(session); err != nil {
-		fmt.Printf("Advertencia: No se pudo guardar la sesión: %v\n", err)
-	}
-
-	fmt.Println("✓ Sesión iniciada exitosamente")
-	fmt.Printf("  Usuario: %s\n", session.Username)
-
-	// Ejecutar comando de conexión VPN si está configurado
-	if config.VPNConnectCmd != "" {
-		fmt.Println("\nConectando VPN...")
-		if err := executeCommand(config.VPNConnectCmd); err != nil {
-			fmt.Printf("⚠️  Error ejecutando comando VPN: %v\n", err)
-		} else {
-			fmt.Println("✓ VPN conectado")
+		if errors.Is(err, ErrAlreadyOnline) {
+			fmt.Println("Si la red sí es de ETECSA, desactive la detección con 'gonauta config set portal.probe_url off'")
 		}
+		printSessionInUseHint(err)
+		os.Exit(1)
 	}
+	warnExpiry(config)
 
 	fmt.Println("\nUse 'gonauta status' para ver el tiempo restante")
 	fmt.Println("Use 'gonauta logout' para cerrar la sesión")
@@ -190,80 +418,52 @@ func handleLogout() {
 	sessionData, err := LoadSession()
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
+		if errors.Is(err, ErrCorruptFile) {
+			fmt.Println("El archivo de sesión no es válido; la sesión debe cerrarse desde el portal de ETECSA")
+		}
 		os.Exit(1)
 	}
 
 	// Cargar configuración para obtener comandos VPN
-	config, err := LoadCredentials()
+	config, err := loadConfigOrEmpty()
 	if err != nil {
 		fmt.Printf("Error cargando configuración: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Verificar si está conectado a través de VPN
-	fmt.Println("Verificando conexión...")
-	ipInfo, err := checkConnection()
-	if err != nil {
-		fmt.Printf("Error verificando conexión: %v\n", err)
-		os.Exit(1)
-	}
-
-	// Si está conectado desde fuera de Cuba (VPN detectado)
-	if ipInfo.CountryCode != "CU" {
-		// Verificar si hay comando de desconexión VPN configurado
-		if config.VPNDisconnectCmd != "" {
-			// Ejecutar desconexión automática
-			fmt.Printf("\n⚠️  Conectado a través de VPN (País: %s, ISP: %s)\n", ipInfo.Country, ipInfo.ISP)
-			fmt.Println("Desconectando VPN...")
-			time.Sleep(2 * time.Second) // Delay de 2 segundos
-			if err := executeCommand(config.VPNDisconnectCmd); err != nil {
-				fmt.Printf("⚠️  Error ejecutando comando VPN: %v\n", err)
-			} else {
-				fmt.Println("✓ VPN desconectado")
-			}
-		} else {
-			// No hay comando de desconexión configurado
-			fmt.Printf("\n⚠️  Conectado a través de VPN\n")
-			fmt.Printf("País: %s\n", ipInfo.Country)
-			fmt.Printf("ISP: %s\n\n", ipInfo.ISP)
+	if _, err := closeSession(config, sessionData); err != nil {
+		if errors.Is(err, ErrVPNWithoutDisconnect) {
 			fmt.Println("Para desconexión automática de VPN, configure un comando de desconexión usando:")
 			fmt.Println("  gonauta login --vpn")
 			fmt.Println("\nNo se puede cerrar sesión mientras esté conectado a través de VPN sin comando de desconexión configurado.")
 			os.Exit(1)
 		}
-	}
-
-	client, err := NewClient()
-	if err != nil {
-		fmt.Printf("Error creando cliente: %v\n", err)
+		fmt.Printf("Error al cerrar sesión: %v\n", err)
 		os.Exit(1)
 	}
+}
 
-	session := NewSession(*sessionData, client)
-
-	fmt.Println("Cerrando sesión...")
-	err = session.Logout()
+func handleStatus() {
+	config, err := loadConfigOrEmpty()
 	if err != nil {
-		fmt.Printf("Error al cerrar sesión: %v\n", err)
+		fmt.Printf("Error cargando configuración: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := DeleteSession(); err != nil {
-		fmt.Printf("Advertencia: No se pudo eliminar el archivo de sesión: %v\n", err)
-	}
-
-	fmt.Println("✓ Sesión cerrada exitosamente")
-}
-
-func handleStatus() {
 	sessionData, err := LoadSession()
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
-		fmt.Println("Use 'gonauta connect' para iniciar sesión primero")
+		if errors.Is(err, ErrNoSession) {
+			state, _ := detectNetworkState(config)
+			fmt.Printf("Red: %s\n", state)
+			if state != NetworkOnline {
+				fmt.Println("Use 'gonauta connect' para iniciar sesión primero")
+			}
+		}
 		os.Exit(1)
 	}
 
-	client, err := NewClient()
+	client, err := NewClient(config)
 	if err != nil {
 		fmt.Printf("Error creando cliente: %v\n", err)
 		os.Exit(1)
@@ -274,47 +474,160 @@ func handleStatus() {
 	remainingTime, err := session.GetRemainingTime()
 	if err != nil {
 		fmt.Printf("Error obteniendo tiempo restante: %v\n", err)
-		// Solo mostrar mensaje de sesión expirada si no es un error de VPN
-		if !strings.Contains(err.Error(), "VPN") {
-			fmt.Println("\nLa sesión puede haber expirado. Use 'gonauta connect' para iniciar sesión nuevamente")
+		if errors.Is(err, ErrSessionExpired) {
+			endStaleSession(config, sessionData)
+			fmt.Println("\nUse 'gonauta connect' para iniciar sesión nuevamente")
+			os.Exit(1)
+		}
+		switch state, _ := detectNetworkState(config); state {
+		case NetworkPortal:
+			// El portal pide iniciar sesión: la sesión guardada ya terminó
+			endStaleSession(config, sessionData)
+			fmt.Println("\nLa sesión ya no está activa en el portal de ETECSA (expiró o se agotó el saldo)")
+			fmt.Println("Use 'gonauta connect' para iniciar sesión nuevamente")
+		case NetworkOffline:
+			fmt.Println("\nNo hay conexión de red")
+		default:
+			// Solo mostrar mensaje de sesión expirada si no es un error de VPN
+			if !strings.Contains(err.Error(), "VPN") {
+				fmt.Println("\nLa sesión puede haber expirado. Use 'gonauta connect' para iniciar sesión nuevamente")
+			}
 		}
 		os.Exit(1)
 	}
+	markSessionSeen(sessionData.RecordID, time.Now())
+
+	lowTime := config.Thresholds.LowTimeMinutes > 0 &&
+		remainingTime < time.Duration(config.Thresholds.LowTimeMinutes)*time.Minute
+
+	record := findSessionRecord(sessionData.RecordID)
+	now := time.Now()
+	var traffic *SessionTraffic
+	if record != nil && record.Traffic != nil {
+		traffic, _ = measureTraffic(config, record, now)
+	}
+
+	if config.jsonOutput() {
+		status := map[string]interface{}{
+			"profile":        currentProfile,
+			"username":       sessionData.Username,
+			"remaining_time": durationToTime(remainingTime),
+			"low_time":       lowTime,
+		}
+		if record != nil {
+			status["elapsed_seconds"] = int(record.Duration(now).Seconds())
+			status["estimated_cost"] = record.RunningCost(now)
+			status["hour_rate"] = record.HourRate
+		}
+		if traffic != nil {
+			status["rx_bytes"] = traffic.RxBytes
+			status["tx_bytes"] = traffic.TxBytes
+		}
+		printJSON(status)
+	} else {
+		fmt.Printf("⏱  Tiempo restante: %s\n", formatDuration(remainingTime))
+		if record != nil {
+			fmt.Printf("   Duración de la sesión: %s\n", formatDuration(record.Duration(now)))
+			fmt.Printf("   Costo estimado: %.2f CUP (%.2f CUP/h)\n", record.RunningCost(now), record.HourRate)
+		}
+		if traffic != nil {
+			fmt.Printf("   Tráfico (%s): ↓ %s  ↑ %s\n", traffic.Interface, formatBytes(traffic.RxBytes), formatBytes(traffic.TxBytes))
+		}
+		if lowTime {
+			fmt.Printf("⚠️  Quedan menos de %d minutos\n", config.Thresholds.LowTimeMinutes)
+		}
+	}
+
+	if !config.jsonOutput() {
+		warnExpiry(config)
+	}
 
-	fmt.Printf("⏱  Tiempo restante: %02d:%02d:%02d\n",
-		remainingTime.Hours,
-		remainingTime.Minutes,
-		remainingTime.Seconds)
+	if lowTime {
+		fireEvent(config, eventLowTime, map[string]string{"USERNAME": sessionData.Username})
+	}
 }
 
-func handleInfo() {
-	config, err := LoadCredentials()
+func handleInfo(args []string) {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	opts := addCredentialFlags(fs)
+	parseCommandFlags(fs, args)
+
+	config, creds, err := resolveCredentials(*opts)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		fmt.Println("Use 'gonauta login' para guardar sus credenciales primero")
 		os.Exit(1)
 	}
 
-	client, err := NewClient()
+	client, err := NewClient(config)
 	if err != nil {
 		fmt.Printf("Error creando cliente: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Println("Obteniendo información del usuario...")
-	userInfo, err := client.GetUserInfo(config.Username, config.Password)
+	if !config.jsonOutput() {
+		fmt.Println("Obteniendo información del usuario...")
+	}
+	userInfo, err := client.GetUserInfo(creds.Username, creds.Password)
 	if err != nil {
 		fmt.Printf("Error obteniendo información: %v\n", err)
 		os.Exit(1)
 	}
+	if err := rememberAccountState(creds.Username, userInfo); err != nil {
+		fmt.Printf("Advertencia: No se pudo guardar el estado de la cuenta: %v\n", err)
+	}
 
-	fmt.Println("\n=== Información del Usuario ===")
-	fmt.Printf("Estado: %s\n", userInfo.Status)
-	fmt.Printf("Créditos: %.2f CUP\n", userInfo.Credits)
-	fmt.Printf("Fecha de expiración: %s\n", userInfo.ExpirationDate)
-	fmt.Printf("Tipo de acceso: %s\n", userInfo.AccessInfo)
-	fmt.Printf("Tiempo disponible: %02d:%02d:%02d\n",
-		userInfo.RemainingTime.Hours,
-		userInfo.RemainingTime.Minutes,
-		userInfo.RemainingTime.Seconds)
+	tariff, err := resolveTariff(config, creds.Username, time.Now())
+	if err != nil {
+		fmt.Printf("Advertencia: %v\n", err)
+	} else {
+		userInfo.RemainingTime = calculateRemainingTime(userInfo.Credits, tariff.HourRate)
+	}
+
+	lowBalance := config.Thresholds.LowBalance > 0 && userInfo.Credits < config.Thresholds.LowBalance
+
+	if config.jsonOutput() {
+		printJSON(userInfo)
+	} else {
+		fmt.Println("\n=== Información del Usuario ===")
+		fmt.Printf("Estado: %s\n", userInfo.Status)
+		fmt.Printf("Créditos: %.2f CUP\n", userInfo.Credits)
+		fmt.Printf("Fecha de expiración: %s%s\n", userInfo.expiration(), userInfo.expirationNote())
+		fmt.Printf("Tipo de acceso: %s\n", userInfo.AccessInfo)
+		fmt.Printf("Tiempo disponible: %s\n", formatDuration(userInfo.RemainingTime))
+		if err == nil {
+			fmt.Printf("Tarifa: %s (%.2f CUP/h)\n", tariff.Name, tariff.HourRate)
+		}
+		if lowBalance {
+			fmt.Printf("\n⚠️  El saldo es menor que %.2f CUP\n", config.Thresholds.LowBalance)
+		}
+	}
+
+	if lowBalance {
+		fireEvent(config, eventLowBalance, map[string]string{
+			"USERNAME": creds.Username,
+			"CREDITS":  fmt.Sprintf("%.2f", userInfo.Credits),
+		})
+	}
+}
+
+func handleProfiles() {
+	profiles, err := listProfiles()
+	if err != nil {
+		fmt.Printf("Error listando perfiles: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(profiles) == 0 {
+		fmt.Println("No hay perfiles guardados. Use 'gonauta login' para crear uno")
+		return
+	}
+
+	for _, name := range profiles {
+		marker := " "
+		if name == currentProfile {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, name)
+	}
 }
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// currentConfigVersion es la versión del esquema de credentials.enc que
+// escribe esta versión de gonauta
+const currentConfigVersion = 2
+
+// configFile es el contenido descifrado de credentials.enc antes de migrarlo
+type configFile struct {
+	path            string
+	raw             []byte
+	document        map[string]interface{}
+	legacyContainer bool
+}
+
+// configMigration actualiza un documento de la versión to-1 a la versión to
+type configMigration struct {
+	to          int
+	description string
+	apply       func(document map[string]interface{}) error
+}
+
+// configMigrations debe mantenerse ordenado por versión
+var configMigrations = []configMigration{
+	{
+		to:          2,
+		description: "Agrupar las opciones por sección (credentials, vpn, portal, thresholds, hooks, output)",
+		apply:       migrateGroupSettings,
+	},
+}
+
+// migrateGroupSettings mueve las opciones planas de la versión 1 a secciones
+func migrateGroupSettings(document map[string]interface{}) error {
+	moves := map[string][2]string{
+		"credential_sources": {"credentials", "sources"},
+		"password_command":   {"credentials", "password_command"},
+		"password_file":      {"credentials", "password_file"},
+		"vpn_connect_cmd":    {"vpn", "connect_cmd"},
+		"vpn_disconnect_cmd": {"vpn", "disconnect_cmd"},
+		"portal_url":         {"portal", "url"},
+		"ip_check_url":       {"portal", "ip_check_url"},
+		"low_balance":        {"thresholds", "low_balance"},
+		"low_time_minutes":   {"thresholds", "low_time_minutes"},
+		"hook_post_connect":  {"hooks", "post_connect"},
+		"hook_pre_logout":    {"hooks", "pre_logout"},
+		"hook_post_logout":   {"hooks", "post_logout"},
+		"hook_event":         {"hooks", "event"},
+		"output_format":      {"output", "format"},
+	}
+
+	for oldKey, target := range moves {
+		value, ok := document[oldKey]
+		if !ok {
+			continue
+		}
+		delete(document, oldKey)
+
+		section, ok := document[target[0]].(map[string]interface{})
+		if !ok {
+			section = make(map[string]interface{})
+			document[target[0]] = section
+		}
+		section[target[1]] = value
+	}
+
+	return nil
+}
+
+// configDocumentVersion devuelve la versión de un documento. Los archivos
+// anteriores al esquema versionado no tienen el campo y son la versión 1.
+func configDocumentVersion(document map[string]interface{}) (int, error) {
+	value, ok := document["version"]
+	if !ok {
+		return 1, nil
+	}
+	number, ok := value.(float64)
+	if !ok || number < 1 || number != float64(int(number)) {
+		return 0, fmt.Errorf("versión de configuración inválida: %v", value)
+	}
+	return int(number), nil
+}
+
+// planConfigMigrations devuelve las migraciones pendientes de un archivo
+func planConfigMigrations(file *configFile) ([]configMigration, error) {
+	version, err := configDocumentVersion(file.document)
+	if err != nil {
+		return nil, err
+	}
+	if version > currentConfigVersion {
+		return nil, fmt.Errorf("%s fue creado por una versión más reciente de gonauta (esquema %d, soportado hasta %d)",
+			file.path, version, currentConfigVersion)
+	}
+
+	var plan []configMigration
+	for _, migration := range configMigrations {
+		if migration.to > version {
+			plan = append(plan, migration)
+		}
+	}
+	return plan, nil
+}
+
+// migrateConfigDocument aplica las migraciones sobre una copia del documento
+func migrateConfigDocument(document map[string]interface{}, plan []configMigration) (map[string]interface{}, error) {
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var migrated map[string]interface{}
+	if err := json.Unmarshal(data, &migrated); err != nil {
+		return nil, err
+	}
+
+	for _, migration := range plan {
+		if err := migration.apply(migrated); err != nil {
+			return nil, fmt.Errorf("migración a la versión %d: %w", migration.to, err)
+		}
+		migrated["version"] = float64(migration.to)
+	}
+	return migrated, nil
+}
+
+// applyConfigMigrations migra el archivo en disco y devuelve la ruta de la
+// copia del original cifrado que se guarda junto a él antes de reescribirlo.
+func applyConfigMigrations(file *configFile, plan []configMigration) (string, error) {
+	version, err := configDocumentVersion(file.document)
+	if err != nil {
+		return "", err
+	}
+
+	migrated, err := migrateConfigDocument(file.document, plan)
+	if err != nil {
+		return "", err
+	}
+
+	backupPath, err := writeConfigBackup(file.path, version, file.raw, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("no se pudo crear la copia de seguridad: %w", err)
+	}
+
+	config, err := decodeConfig(migrated)
+	if err != nil {
+		return "", err
+	}
+	if err := SaveConfig(config); err != nil {
+		return "", err
+	}
+
+	file.document = migrated
+	return backupPath, nil
+}
+
+// writeConfigBackup guarda la copia de seguridad de una versión sin
+// sobrescribir nunca una anterior: si ya existe, la nueva lleva la fecha
+func writeConfigBackup(path string, version int, data []byte, now time.Time) (string, error) {
+	backupPath := configBackupPath(path, version)
+	err := writeFileExclusive(backupPath, data)
+	if os.IsExist(err) {
+		backupPath = fmt.Sprintf("%s.v%d.%s.bak", path, version, now.Format("20060102-150405"))
+		err = writeFileExclusive(backupPath, data)
+	}
+	if err != nil {
+		return "", err
+	}
+	return backupPath, nil
+}
+
+// writeFileExclusive crea un archivo nuevo y falla si ya existe
+func writeFileExclusive(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// configBackupPath devuelve la ruta de la copia de seguridad de una versión
+func configBackupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
+
+// flattenDocument convierte un documento en pares "sección.opción" = valor,
+// ocultando la contraseña
+func flattenDocument(prefix string, document map[string]interface{}, out map[string]string) {
+	for key, value := range document {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flattenDocument(name, v, out)
+		default:
+			if name == "password" {
+				out[name] = "********"
+				continue
+			}
+			data, _ := json.Marshal(v)
+			out[name] = string(data)
+		}
+	}
+}
+
+// describeConfigChanges devuelve las líneas de diferencia entre dos documentos
+func describeConfigChanges(before, after map[string]interface{}) []string {
+	oldValues := make(map[string]string)
+	newValues := make(map[string]string)
+	flattenDocument("", before, oldValues)
+	flattenDocument("", after, newValues)
+
+	names := make(map[string]bool)
+	for name := range oldValues {
+		names[name] = true
+	}
+	for name := range newValues {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var lines []string
+	for _, name := range sorted {
+		oldValue, hadOld := oldValues[name]
+		newValue, hasNew := newValues[name]
+		switch {
+		case hadOld && !hasNew:
+			lines = append(lines, fmt.Sprintf("- %s = %s", name, oldValue))
+		case !hadOld && hasNew:
+			lines = append(lines, fmt.Sprintf("+ %s = %s", name, newValue))
+		case oldValue != newValue:
+			lines = append(lines, fmt.Sprintf("- %s = %s", name, oldValue))
+			lines = append(lines, fmt.Sprintf("+ %s = %s", name, newValue))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// v1Config es un credentials.enc tal como lo guardaba la primera versión de
+// gonauta: opciones planas, sin campo version y cifrado sin cabecera
+const v1Config = `{"username":"usuario@nauta.com.cu","password":"secreta","vpn_connect_cmd":"wg-quick up nauta","vpn_disconnect_cmd":"wg-quick down nauta"}`
+
+// writeV1Config guarda un archivo de la versión 1 en el perfil activo y
+// devuelve su contenido cifrado
+func writeV1Config(t *testing.T, document string) []byte {
+	t.Helper()
+	raw, err := encryptWithAAD([]byte(document), getEncryptionKey(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configPath, err := getConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, raw, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestLoadConfigMigratesV1(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	raw := writeV1Config(t, v1Config)
+
+	config, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Username != testUsername || config.Password != "secreta" {
+		t.Errorf("LoadConfig: usuario %q y contraseña %q, se esperaban los del archivo", config.Username, config.Password)
+	}
+	if config.VPN.ConnectCmd != "wg-quick up nauta" || config.VPN.DisconnectCmd != "wg-quick down nauta" {
+		t.Errorf("LoadConfig: vpn = %+v, se esperaban los comandos de la versión 1", config.VPN)
+	}
+
+	configPath, _ := getConfigPath()
+	backup, err := os.ReadFile(configBackupPath(configPath, 1))
+	if err != nil {
+		t.Fatalf("copia de seguridad: %v", err)
+	}
+	if !bytes.Equal(backup, raw) {
+		t.Error("la copia de seguridad no coincide con el archivo original")
+	}
+
+	// El archivo se reescribe con el esquema y el contenedor actuales
+	file, err := readConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, _ := configDocumentVersion(file.document); version != currentConfigVersion || file.legacyContainer {
+		t.Errorf("archivo migrado: versión %d, formato anterior %v; se esperaba la versión %d cifrada con cabecera",
+			version, file.legacyContainer, currentConfigVersion)
+	}
+	if _, ok := file.document["vpn_connect_cmd"]; ok {
+		t.Error("archivo migrado: vpn_connect_cmd sigue en el nivel superior")
+	}
+	if plan, _ := planConfigMigrations(file); len(plan) != 0 {
+		t.Errorf("archivo migrado: %d migraciones pendientes, se esperaba ninguna", len(plan))
+	}
+}
+
+func TestConfigBackupNotOverwritten(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	first := writeV1Config(t, v1Config)
+	if _, err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Otro archivo de la versión 1, por ejemplo restaurado a mano
+	second := writeV1Config(t, `{"username":"otro@nauta.com.cu","password":"otra"}`)
+	if _, err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+
+	configPath, _ := getConfigPath()
+	backup, err := os.ReadFile(configBackupPath(configPath, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backup, first) {
+		t.Error("la segunda migración sobrescribió la primera copia de seguridad")
+	}
+
+	dated, err := filepath.Glob(configPath + ".v1.*.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dated) != 1 {
+		t.Fatalf("copias con fecha = %v, se esperaba una", dated)
+	}
+	if data, _ := os.ReadFile(dated[0]); !bytes.Equal(data, second) {
+		t.Errorf("%s no coincide con el segundo archivo original", dated[0])
+	}
+}
+
+func TestWriteConfigBackup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.enc")
+	now := time.Date(2026, 10, 19, 15, 30, 0, 0, time.UTC)
+
+	got, err := writeConfigBackup(path, 1, []byte("a"), now)
+	if err != nil || got != path+".v1.bak" {
+		t.Errorf("writeConfigBackup = %s, %v; se esperaba %s", got, err, path+".v1.bak")
+	}
+	got, err = writeConfigBackup(path, 1, []byte("b"), now)
+	if want := path + ".v1.20261019-153000.bak"; err != nil || got != want {
+		t.Errorf("writeConfigBackup = %s, %v; se esperaba %s", got, err, want)
+	}
+	// Si también existe la copia con fecha se falla antes que sobrescribirla
+	if _, err := writeConfigBackup(path, 1, []byte("c"), now); err == nil {
+		t.Error("writeConfigBackup sobrescribió una copia existente")
+	}
+}
+
+func TestMigrateGroupSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		v1   string
+		want string
+	}{
+		{
+			name: "archivo de la primera versión",
+			v1:   v1Config,
+			want: `{"version":2,"username":"usuario@nauta.com.cu","password":"secreta","vpn":{"connect_cmd":"wg-quick up nauta","disconnect_cmd":"wg-quick down nauta"}}`,
+		},
+		{
+			name: "opciones planas de las versiones de desarrollo",
+			v1: `{"username":"u","credential_sources":["keyring","file"],"password_command":"pass nauta",` +
+				`"portal_url":"https://portal","low_balance":5,"hook_event":"notify","output_format":"json"}`,
+			want: `{"version":2,"username":"u","credentials":{"sources":["keyring","file"],"password_command":"pass nauta"},` +
+				`"portal":{"url":"https://portal"},"thresholds":{"low_balance":5},"hooks":{"event":"notify"},"output":{"format":"json"}}`,
+		},
+		{
+			name: "se conservan las secciones existentes",
+			v1:   `{"vpn":{"connect_cmd":"a"},"vpn_disconnect_cmd":"b"}`,
+			want: `{"version":2,"vpn":{"connect_cmd":"a","disconnect_cmd":"b"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var document, want map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.v1), &document); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			plan, err := planConfigMigrations(&configFile{document: document})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := migrateConfigDocument(document, plan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("migrateConfigDocument = %v, se esperaba %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDescribeConfigChangesDryRun(t *testing.T) {
+	var document map[string]interface{}
+	if err := json.Unmarshal([]byte(v1Config), &document); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := planConfigMigrations(&configFile{document: document})
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrated, err := migrateConfigDocument(document, plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// --dry-run trabaja sobre una copia: el documento leído no cambia
+	if _, ok := document["version"]; ok {
+		t.Error("migrateConfigDocument modificó el documento original")
+	}
+
+	want := []string{
+		`+ version = 2`,
+		`+ vpn.connect_cmd = "wg-quick up nauta"`,
+		`+ vpn.disconnect_cmd = "wg-quick down nauta"`,
+		`- vpn_connect_cmd = "wg-quick up nauta"`,
+		`- vpn_disconnect_cmd = "wg-quick down nauta"`,
+	}
+	if got := describeConfigChanges(document, migrated); !reflect.DeepEqual(got, want) {
+		t.Errorf("describeConfigChanges =\n%q\nse esperaba\n%q", got, want)
+	}
+
+	// La contraseña nunca aparece en la diferencia
+	changed := map[string]interface{}{"password": "nueva"}
+	for _, line := range describeConfigChanges(document, changed) {
+		if strings.Contains(line, "secreta") || strings.Contains(line, "nueva") {
+			t.Errorf("describeConfigChanges muestra la contraseña: %s", line)
+		}
+	}
+}
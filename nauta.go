@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,32 +19,36 @@ import (
 
 const (
 	BaseURL           = "https://secure.etecsa.net:8443"
-	HourRate          = 12.5
-	NationalHourRate  = 2.5
 	MaxTimeoutSeconds = 30
 	IPCheckURL        = "http://ip-api.com/json/"
 )
 
-// Time representa el tiempo restante
+// Time es la representación JSON de un tiempo restante
 type Time struct {
 	Hours   int `json:"hours"`
 	Minutes int `json:"minutes"`
 	Seconds int `json:"seconds"`
 }
 
-// UserInfo contiene la información del usuario
+// UserInfo contiene la información del usuario. Su codificación JSON está en
+// userinfo.go.
 type UserInfo struct {
-	Status         string  `json:"status"`
-	Credits        float64 `json:"credits"`
-	ExpirationDate string  `json:"expiration_date"`
-	AccessInfo     string  `json:"access_info"`
-	RemainingTime  Time    `json:"remaining_time"`
+	Status         AccountStatus
+	Credits        float64
+	ExpirationDate *time.Time
+	ExpirationText string // texto del portal si la fecha no se reconoce
+	AccessInfo     AccessInfo
+	RemainingTime  time.Duration
 }
 
 // SessionData contiene los datos de la sesión
 type SessionData struct {
-	Username string `json:"username"`
-	UUID     string `json:"uuid"`
+	Username  string    `json:"username"`
+	UUID      string    `json:"uuid"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+	RecordID  string    `json:"record_id,omitempty"`
+	VPN       bool      `json:"vpn,omitempty"`    // se ejecutó vpn.connect_cmd con éxito
+	Forced    bool      `json:"forced,omitempty"` // se abrió con --force pese a un límite de gasto
 }
 
 // IPInfo contiene la información de geolocalización IP
@@ -68,27 +73,39 @@ type IPInfo struct {
 type Client struct {
 	httpClient *http.Client
 	cookieJar  *cookiejar.Jar
+	baseURL    string
+	ipCheckURL string
+	retry      RetryPolicy
 }
 
-// NewClient crea una nueva instancia del cliente Nauta
-func NewClient() (*Client, error) {
+// NewClient crea una nueva instancia del cliente Nauta usando los endpoints
+// configurados en el perfil
+func NewClient(config *Config) (*Client, error) {
 	jar, err := cookiejar.New(nil)
 	if err != nil {
 		return nil, err
 	}
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Client{
 		httpClient: &http.Client{
-			Jar:     jar,
-			Timeout: MaxTimeoutSeconds * time.Second,
+			Jar:       jar,
+			Timeout:   MaxTimeoutSeconds * time.Second,
+			Transport: transport,
 		},
-		cookieJar: jar,
+		cookieJar:  jar,
+		baseURL:    config.portalURL(),
+		ipCheckURL: config.ipCheckURL(),
+		retry:      config.retryPolicy(),
 	}, nil
 }
 
 // checkConnection verifica la conectividad y detecta el uso de VPN
-func checkConnection() (*IPInfo, error) {
-	resp, err := http.Get(IPCheckURL)
+func (c *Client) checkConnection() (*IPInfo, error) {
+	resp, err := c.httpClient.Get(c.ipCheckURL)
 	if err != nil {
 		return nil, fmt.Errorf("no hay conexión a internet: %w", err)
 	}
@@ -111,232 +128,215 @@ func checkConnection() (*IPInfo, error) {
 	return &ipInfo, nil
 }
 
-// getLoginParams extrae los parámetros ocultos del formulario de login
-func (c *Client) getLoginParams(body string) (map[string]string, error) {
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
-	if err != nil {
-		return nil, err
-	}
-
-	params := make(map[string]string)
-	doc.Find("#formulario input[type='hidden']").Each(func(i int, s *goquery.Selection) {
-		name, _ := s.Attr("name")
-		value, _ := s.Attr("value")
-		params[name] = value
-	})
+// Errores del portal sobre la cuenta. Nunca se reintentan.
+var (
+	ErrWrongPassword = errors.New("el nombre de usuario o contraseña son incorrectos")
+	ErrNoBalance     = errors.New("no tiene saldo disponible")
+	ErrAccountInUse  = errors.New("su cuenta está siendo usada")
+	ErrNotAuthorized = errors.New("no se pudo autorizar al usuario")
+)
 
-	return params, nil
+// ErrSessionExpired indica que el portal ya no reconoce la sesión: expiró, se
+// agotó el saldo o se cerró desde otro lugar
+var ErrSessionExpired = errors.New("la sesión ya no está activa en el portal de ETECSA")
+
+// sessionExpiredReply es la respuesta del portal a las consultas con el UUID
+// de una sesión que ya terminó
+const sessionExpiredReply = "errorop"
+
+// ErrLoginUncertain indica que el envío del login falló sin una respuesta
+// clara del portal, que pudo haber abierto la sesión igualmente
+var ErrLoginUncertain = errors.New("el portal no confirmó el inicio de sesión y puede haberla abierto igualmente")
+
+// portalErrors asocia los mensajes de error del portal, ya normalizados (ver
+// portalText), con su error
+var portalErrors = []struct {
+	message string
+	err     error
+}{
+	{"el nombre de usuario o contrasena son incorrectos", ErrWrongPassword},
+	{"su tarjeta no tiene saldo disponible", ErrNoBalance},
+	{"el usuario ya esta conectado", ErrAccountInUse},
+	{"no se pudo autorizar al usuario", ErrNotAuthorized},
 }
 
-// extractUUID extrae el UUID de la respuesta del login
-func extractUUID(body string) (string, error) {
-	re := regexp.MustCompile(`ATTRIBUTE_UUID=(\w*)&`)
-	matches := re.FindStringSubmatch(body)
-	if len(matches) < 2 {
-		return "", errors.New("UUID no encontrado en la respuesta")
-	}
-	return matches[1], nil
+// checkPortalErrors detecta los mensajes de error del portal en una respuesta.
+// Se buscan en el texto normalizado, así que no importa si el portal escribe
+// los acentos como entidades HTML o escapes de JavaScript.
+func checkPortalErrors(body, username string) error {
+	text := portalText(body)
+	for _, candidate := range portalErrors {
+		if !strings.Contains(text, candidate.message) {
+			continue
+		}
+		if candidate.err == ErrNoBalance {
+			return fmt.Errorf("su cuenta %s %w", username, ErrNoBalance)
+		}
+		return candidate.err
+	}
+	return nil
 }
 
-// Login inicia sesión en Nauta
-func (c *Client) Login(username, password string) (*SessionData, error) {
-	// Verificar conectividad
-	ipInfo, err := checkConnection()
+// loginForm obtiene la página inicial del portal y devuelve el formulario de
+// login con las credenciales. Una página sin formulario se considera un fallo
+// pasajero del portal.
+func (c *Client) loginForm(ctx context.Context, username, password string) (url.Values, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL, nil)
 	if err != nil {
 		return nil, err
 	}
-
-	// Verificar si está conectado desde Cuba (posible VPN)
-	if ipInfo.CountryCode != "CU" {
-		fmt.Printf("\n⚠️  Conectado a través de VPN\n")
-		fmt.Printf("País: %s\n", ipInfo.Country)
-		fmt.Printf("ISP: %s\n\n", ipInfo.ISP)
-	}
-
-	// Obtener la página inicial
-	resp, err := c.httpClient.Get(BaseURL)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("error de conexión: %w. Comprueba que estás conectado a una WiFi de ETECSA", err)
 	}
 	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return nil, err
+	}
 
-	// Leer el body
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	_, body, err := readPage(resp)
 	if err != nil {
-		return nil, err
+		return nil, transient(err)
 	}
-	body, _ := doc.Html()
 
-	// Obtener parámetros del login
 	loginParams, err := c.getLoginParams(body)
 	if err != nil {
 		return nil, err
 	}
+	if len(loginParams) == 0 {
+		return nil, transient(errors.New("el portal devolvió una página incompleta (formulario de login sin campos)"))
+	}
 
-	// Preparar datos del formulario
 	formData := url.Values{}
 	for key, value := range loginParams {
 		formData.Set(key, value)
 	}
 	formData.Set("username", username)
 	formData.Set("password", password)
+	return formData, nil
+}
 
-	// Hacer login
-	resp, err = c.httpClient.PostForm(BaseURL+"/LoginServlet", formData)
+// postPage envía un formulario al portal y devuelve la página de respuesta,
+// ya analizada y como HTML decodificado a UTF-8
+func (c *Client) postPage(ctx context.Context, path string, formData url.Values) (*goquery.Document, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, strings.NewReader(formData.Encode()))
 	if err != nil {
-		return nil, fmt.Errorf("error de conexión: %w", err)
+		return nil, "", err
 	}
-	defer resp.Body.Close()
-
-	// Leer respuesta
-	doc, err = goquery.NewDocumentFromReader(resp.Body)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
-	}
-	responseBody, _ := doc.Html()
-
-	// Validar errores
-	if strings.Contains(responseBody, "El nombre de usuario o contraseña son incorrectos.") {
-		return nil, errors.New("el nombre de usuario o contraseña son incorrectos")
-	}
-	if strings.Contains(responseBody, "Su tarjeta no tiene saldo disponible") {
-		return nil, fmt.Errorf("su cuenta %s no tiene saldo disponible", username)
+		return nil, "", fmt.Errorf("error de conexión: %w", err)
 	}
-	if strings.Contains(responseBody, "El usuario ya está conectado.") {
-		return nil, errors.New("su cuenta está siendo usada")
-	}
-	if strings.Contains(responseBody, "No se pudo autorizar al usuario") {
-		return nil, errors.New("no se pudo autorizar al usuario")
+	defer resp.Body.Close()
+	if err := checkStatus(resp); err != nil {
+		return nil, "", err
 	}
 
-	// Extraer UUID
-	uuid, err := extractUUID(responseBody)
+	doc, body, err := readPage(resp)
 	if err != nil {
-		return nil, fmt.Errorf("no se ha podido obtener los datos de la sesión: %w", err)
+		return nil, "", transient(err)
 	}
-
-	return &SessionData{
-		Username: username,
-		UUID:     uuid,
-	}, nil
+	return doc, body, nil
 }
 
-// extractUserInfo extrae la información del usuario del HTML
-func extractUserInfo(body string) (*UserInfo, error) {
-	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+// Login inicia sesión en Nauta
+func (c *Client) Login(username, password string) (*SessionData, error) {
+	// Verificar conectividad
+	ipInfo, err := c.checkConnection()
 	if err != nil {
 		return nil, err
 	}
 
-	statusText := strings.TrimSpace(doc.Find("#sessioninfo tr:nth-child(1) td:nth-child(2)").Text())
-	creditsText := strings.TrimSpace(doc.Find("#sessioninfo tr:nth-child(2) td:nth-child(2)").Text())
-	expirationDateText := strings.TrimSpace(doc.Find("#sessioninfo tr:nth-child(3) td:nth-child(2)").Text())
-	accessInfoText := strings.TrimSpace(doc.Find("#sessioninfo tr:nth-child(4) td:nth-child(2)").Text())
-
-	status := "Disabled"
-	if statusText == "Activa" {
-		status = "Active"
-	}
-
-	creditsStr := strings.Replace(creditsText, " CUP", "", 1)
-	credits, err := strconv.ParseFloat(creditsStr, 64)
-	if err != nil {
-		return nil, fmt.Errorf("error parseando créditos: %w", err)
-	}
-
-	expirationDate := expirationDateText
-	if expirationDateText == "No especificada" {
-		expirationDate = "None"
-	}
-
-	accessInfo := accessInfoText
-	if accessInfoText == "Acceso desde todas las áreas de Internet" {
-		accessInfo = "All"
+	// Verificar si está conectado desde Cuba (posible VPN)
+	if ipInfo.CountryCode != "CU" {
+		fmt.Printf("\n⚠️  Conectado a través de VPN\n")
+		fmt.Printf("País: %s\n", ipInfo.Country)
+		fmt.Printf("ISP: %s\n\n", ipInfo.ISP)
 	}
 
-	return &UserInfo{
-		Status:         status,
-		Credits:        credits,
-		ExpirationDate: expirationDate,
-		AccessInfo:     accessInfo,
-	}, nil
+	return c.loginPortal(username, password)
 }
 
-// calculateRemainingTime calcula el tiempo restante basado en créditos
-func calculateRemainingTime(credits, rate float64) Time {
-	totalHours := credits / rate
-	hours := int(totalHours)
-
-	remainingFraction := totalHours - float64(hours)
-	totalMinutes := remainingFraction * 60
-	minutes := int(totalMinutes)
-
-	remainingSeconds := totalMinutes - float64(minutes)
-	seconds := int(remainingSeconds * 60)
-
-	return Time{
-		Hours:   hours,
-		Minutes: minutes,
-		Seconds: seconds,
-	}
-}
-
-// GetUserInfo obtiene la información del usuario
-func (c *Client) GetUserInfo(username, password string) (*UserInfo, error) {
-	// Obtener la página inicial
-	resp, err := c.httpClient.Get(BaseURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+// loginPortal inicia sesión en el portal sin comprobar antes la conexión. Se
+// usa cuando ya se sabe que el portal de ETECSA intercepta el tráfico. Solo se
+// reintenta la carga del formulario. El envío a LoginServlet no se reintenta
+// nunca, tampoco si la respuesta llega sin UUID: el portal pudo abrir la
+// sesión, y repetirlo fallaría con ErrAccountInUse dejándola abierta sin su
+// UUID. Esos fallos se devuelven como ErrLoginUncertain.
+func (c *Client) loginPortal(username, password string) (*SessionData, error) {
+	var formData url.Values
+	err := c.retry.do("Carga del formulario de inicio de sesión", func(ctx context.Context) error {
+		var err error
+		formData, err = c.loginForm(ctx, username, password)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	body, _ := doc.Html()
 
-	// Obtener parámetros del login
-	loginParams, err := c.getLoginParams(body)
+	_, responseBody, err := c.postPage(context.Background(), "/LoginServlet", formData)
 	if err != nil {
+		if isTransient(err) {
+			return nil, fmt.Errorf("%w: %v", ErrLoginUncertain, err)
+		}
 		return nil, err
 	}
 
-	// Preparar datos del formulario
-	formData := url.Values{}
-	for key, value := range loginParams {
-		formData.Set(key, value)
-	}
-	formData.Set("username", username)
-	formData.Set("password", password)
-
-	// Consultar información del usuario
-	resp, err = c.httpClient.PostForm(BaseURL+"/EtecsaQueryServlet", formData)
-	if err != nil {
+	// Validar errores
+	if err := checkPortalErrors(responseBody, username); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	doc, err = goquery.NewDocumentFromReader(resp.Body)
+	// Extraer UUID. Sin él la página llegó incompleta.
+	uuid, err := extractUUID(responseBody)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: no se ha podido obtener los datos de la sesión: %v", ErrLoginUncertain, err)
 	}
-	responseBody, _ := doc.Html()
 
-	userInfo, err := extractUserInfo(responseBody)
-	if err != nil {
-		return nil, err
-	}
-
-	// Determinar la tarifa según el tipo de cuenta
-	rate := HourRate
-	if strings.Contains(username, "@nauta.co.cu") {
-		rate = NationalHourRate
-	}
+	return &SessionData{
+		Username: username,
+		UUID:     uuid,
+	}, nil
+}
 
-	userInfo.RemainingTime = calculateRemainingTime(userInfo.Credits, rate)
+// calculateRemainingTime calcula el tiempo restante basado en créditos
+func calculateRemainingTime(credits, rate float64) time.Duration {
+	seconds := int64(credits / rate * 3600)
+	return time.Duration(seconds) * time.Second
+}
 
-	return userInfo, nil
+// GetUserInfo obtiene la información del usuario. RemainingTime se calcula
+// después con la tarifa del perfil (ver resolveTariff). Una cuenta sin saldo
+// se devuelve con crédito 0.
+func (c *Client) GetUserInfo(username, password string) (*UserInfo, error) {
+	var userInfo *UserInfo
+	err := c.retry.do("Consulta de la cuenta", func(ctx context.Context) error {
+		formData, err := c.loginForm(ctx, username, password)
+		if err != nil {
+			return err
+		}
+
+		// Consultar información del usuario
+		_, responseBody, err := c.postPage(ctx, "/EtecsaQueryServlet", formData)
+		if err != nil {
+			return err
+		}
+
+		err = checkPortalErrors(responseBody, username)
+		if errors.Is(err, ErrNoBalance) {
+			// Una cuenta sin saldo es válida; solo impide iniciar sesión
+			userInfo = noBalanceUserInfo(responseBody)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		userInfo, err = extractUserInfo(responseBody)
+		return err
+	})
+	return userInfo, err
 }
 
 // Session representa una sesión activa de Nauta
@@ -354,11 +354,11 @@ func NewSession(data SessionData, client *Client) *Session {
 }
 
 // parseTime parsea una cadena de tiempo en formato HH:MM:SS
-func parseTime(value string) (Time, error) {
+func parseTime(value string) (time.Duration, error) {
 	re := regexp.MustCompile(`(\d+):([\d]{2}):([\d]{2})`)
 	matches := re.FindStringSubmatch(value)
 	if len(matches) < 4 {
-		return Time{}, errors.New("formato de tiempo inválido")
+		return 0, errors.New("formato de tiempo inválido")
 	}
 
 	hours, _ := strconv.Atoi(matches[1])
@@ -369,24 +369,24 @@ func parseTime(value string) (Time, error) {
 		Hours:   hours,
 		Minutes: minutes,
 		Seconds: seconds,
-	}, nil
+	}.Duration(), nil
 }
 
 // GetRemainingTime obtiene el tiempo restante de la sesión
-func (s *Session) GetRemainingTime() (*Time, error) {
+func (s *Session) GetRemainingTime() (time.Duration, error) {
 	if s.Data.UUID == "" || s.Data.Username == "" {
-		return nil, fmt.Errorf("sesión inválida: %+v", s.Data)
+		return 0, fmt.Errorf("sesión inválida: %+v", s.Data)
 	}
 
 	// Verificar conectividad
-	ipInfo, err := checkConnection()
+	ipInfo, err := s.client.checkConnection()
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 
 	// Bloquear si está conectado desde fuera de Cuba (VPN)
 	if ipInfo.CountryCode != "CU" {
-		return nil, fmt.Errorf("No se puede obtener el estado de la sesión cuando está conectado a través de VPN (País: %s, ISP: %s)", ipInfo.Country, ipInfo.ISP)
+		return 0, fmt.Errorf("No se puede obtener el estado de la sesión cuando está conectado a través de VPN (País: %s, ISP: %s)", ipInfo.Country, ipInfo.ISP)
 	}
 
 	formData := url.Values{}
@@ -394,30 +394,26 @@ func (s *Session) GetRemainingTime() (*Time, error) {
 	formData.Set("ATTRIBUTE_UUID", s.Data.UUID)
 	formData.Set("username", s.Data.Username)
 
-	resp, err := s.client.httpClient.PostForm(BaseURL+"/EtecsaQueryServlet", formData)
+	doc, html, err := s.client.postPage(context.Background(), "/EtecsaQueryServlet", formData)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer resp.Body.Close()
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return nil, err
+	text := strings.TrimSpace(doc.Text())
+	if text == sessionExpiredReply {
+		return 0, ErrSessionExpired
 	}
-	body := doc.Text()
-
-	remainingTime, err := parseTime(body)
+	remaining, err := parseTime(text)
 	if err != nil {
-		return nil, err
+		return 0, pageChanged("tiempo restante", html, []string{"el tiempo en formato HH:MM:SS"}, "")
 	}
-
-	return &remainingTime, nil
+	return remaining, nil
 }
 
 // Logout cierra la sesión
 func (s *Session) Logout() error {
 	// Verificar conectividad
-	ipInfo, err := checkConnection()
+	ipInfo, err := s.client.checkConnection()
 	if err != nil {
 		return err
 	}
@@ -434,21 +430,19 @@ func (s *Session) Logout() error {
 	formData.Set("username", s.Data.Username)
 	formData.Set("remove", "1")
 
-	resp, err := s.client.httpClient.PostForm(BaseURL+"/LogoutServlet", formData)
-	if err != nil {
-		return fmt.Errorf("error al cerrar sesión: %w", err)
-	}
-	defer resp.Body.Close()
+	// Reintentar es seguro: cerrar una sesión ya cerrada solo devuelve un
+	// error del portal
+	return s.client.retry.do("Cierre de sesión", func(ctx context.Context) error {
+		doc, _, err := s.client.postPage(ctx, "/LogoutServlet", formData)
+		if err != nil {
+			return fmt.Errorf("error al cerrar sesión: %w", err)
+		}
+		body := doc.Text()
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return err
-	}
-	body := doc.Text()
+		if strings.Contains(body, "logoutcallback('SUCCESS')") {
+			return nil
+		}
 
-	if strings.Contains(body, "logoutcallback('SUCCESS')") {
-		return nil
-	}
-
-	return fmt.Errorf("fallo al cerrar sesión: %s", body)
+		return fmt.Errorf("fallo al cerrar sesión: %s", body)
+	})
 }
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// testUsername es el usuario grabado en las trazas de testdata
+const testUsername = "usuario@nauta.com.cu"
+
+// newTestClient devuelve un cliente que responde con la traza testdata/<name>.har.
+// Las trazas están ocultas: la contraseña no se compara y el UUID de la sesión
+// es redactedValue. No se reintenta, para que cada página incompleta de la
+// traza corresponda a un intento.
+func newTestClient(t *testing.T, name string) *Client {
+	t.Helper()
+	// Las páginas que no se reconocen se guardan en el directorio de gonauta
+	t.Setenv("HOME", t.TempDir())
+
+	har, err := loadHAR(filepath.Join("testdata", name+".har"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(&Config{Retry: RetrySettings{MaxAttempts: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.httpClient.Transport = newReplayTransport(har)
+	return client
+}
+
+// testSession devuelve la sesión grabada en las trazas
+func testSession(client *Client) *Session {
+	return NewSession(SessionData{Username: testUsername, UUID: redactedValue}, client)
+}
+
+func TestClientSuccess(t *testing.T) {
+	client := newTestClient(t, "success")
+
+	session, err := client.Login(testUsername, "")
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if session.Username != testUsername || session.UUID != redactedValue {
+		t.Errorf("Login = %+v, se esperaba el usuario %s y el UUID %s", session, testUsername, redactedValue)
+	}
+
+	userInfo, err := client.GetUserInfo(testUsername, "")
+	if err != nil {
+		t.Fatalf("GetUserInfo: %v", err)
+	}
+	if userInfo.Status != StatusActive || userInfo.Credits != 95.5 {
+		t.Errorf("GetUserInfo = %s, %.2f CUP; se esperaba %s, 95.50 CUP", userInfo.Status, userInfo.Credits, StatusActive)
+	}
+	if userInfo.ExpirationDate == nil || userInfo.ExpirationDate.Format("2006-01-02") != "2026-12-31" {
+		t.Errorf("GetUserInfo: fecha de expiración %v, se esperaba 2026-12-31", userInfo.ExpirationDate)
+	}
+
+	// La página de la cuenta llega en ISO-8859-1 (ver TestReplayLatin1)
+	if want := "Acceso desde todas las áreas de Internet"; userInfo.AccessInfo.Text != want || userInfo.AccessInfo.Scope != AccessAll {
+		t.Errorf("GetUserInfo: acceso %q (%s), se esperaba %q (%s)", userInfo.AccessInfo.Text, userInfo.AccessInfo.Scope, want, AccessAll)
+	}
+
+	remaining, err := testSession(client).GetRemainingTime()
+	if err != nil {
+		t.Fatalf("GetRemainingTime: %v", err)
+	}
+	if want := 3*time.Hour + 49*time.Minute + 12*time.Second; remaining != want {
+		t.Errorf("GetRemainingTime = %s, se esperaba %s", remaining, want)
+	}
+
+	if err := testSession(client).Logout(); err != nil {
+		t.Errorf("Logout: %v", err)
+	}
+}
+
+func TestLoginPortalErrors(t *testing.T) {
+	tests := []struct {
+		har  string
+		want error
+	}{
+		{"wrong_password", ErrWrongPassword},
+		{"no_balance", ErrNoBalance},
+		{"account_in_use", ErrAccountInUse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.har, func(t *testing.T) {
+			_, err := newTestClient(t, tt.har).Login(testUsername, "")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Login: %v, se esperaba %v", err, tt.want)
+			}
+			if isTransient(err) {
+				t.Errorf("Login: %v no debe reintentarse", err)
+			}
+		})
+	}
+}
+
+func TestGetUserInfoWrongPassword(t *testing.T) {
+	_, err := newTestClient(t, "wrong_password").GetUserInfo(testUsername, "")
+	if !errors.Is(err, ErrWrongPassword) {
+		t.Errorf("GetUserInfo: %v, se esperaba %v", err, ErrWrongPassword)
+	}
+}
+
+func TestGetUserInfoNoBalance(t *testing.T) {
+	userInfo, err := newTestClient(t, "no_balance").GetUserInfo(testUsername, "")
+	if err != nil {
+		t.Fatalf("GetUserInfo: %v", err)
+	}
+	if userInfo.Status != StatusActive || userInfo.Credits != 0 {
+		t.Errorf("GetUserInfo = %s, %.2f CUP; se esperaba %s, 0.00 CUP", userInfo.Status, userInfo.Credits, StatusActive)
+	}
+}
+
+func TestClientHalfPage(t *testing.T) {
+	client := newTestClient(t, "half_page")
+	var pageErr *PageChangedError
+
+	// Primero llega la página de login cortada antes del formulario
+	_, err := client.Login(testUsername, "")
+	if !errors.As(err, &pageErr) || !isTransient(err) {
+		t.Errorf("Login: %v, se esperaba un PageChangedError pasajero", err)
+	}
+
+	// Después la respuesta del login llega sin el UUID de la sesión. El
+	// portal pudo abrirla, así que no se reintenta.
+	_, err = client.Login(testUsername, "")
+	if !errors.Is(err, ErrLoginUncertain) || isTransient(err) {
+		t.Errorf("Login: %v, se esperaba %v sin reintentar", err, ErrLoginUncertain)
+	}
+
+	_, err = client.GetUserInfo(testUsername, "")
+	if !errors.As(err, &pageErr) || len(pageErr.Missing) == 0 {
+		t.Errorf("GetUserInfo: %v, se esperaba un PageChangedError con los datos que faltan", err)
+	}
+
+	_, err = testSession(client).GetRemainingTime()
+	if !errors.As(err, &pageErr) {
+		t.Errorf("GetRemainingTime: %v, se esperaba un PageChangedError", err)
+	}
+}
+
+func TestGetRemainingTimeSessionExpired(t *testing.T) {
+	_, err := testSession(newTestClient(t, "session_expired")).GetRemainingTime()
+	if !errors.Is(err, ErrSessionExpired) {
+		t.Errorf("GetRemainingTime: %v, se esperaba %v", err, ErrSessionExpired)
+	}
+}
+
+// TestReplayLatin1 comprueba que las trazas conservan las páginas del portal
+// en ISO-8859-1 tal como llegaron, para que la reproducción pase por la
+// decodificación igual que una sesión real
+func TestReplayLatin1(t *testing.T) {
+	har, err := loadHAR(filepath.Join("testdata", "success.har"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range har.Log.Entries {
+		if !strings.HasSuffix(entry.Request.URL, "/EtecsaQueryServlet") || entry.Request.Method != "POST" {
+			continue
+		}
+		body, err := entry.Response.Content.body()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), "Cr\xe9dito") || utf8.Valid(body) {
+			continue
+		}
+		if !strings.Contains(entry.Response.Content.MimeType, "ISO-8859-1") {
+			t.Errorf("la página de la cuenta se sirve como %q, se esperaba ISO-8859-1", entry.Response.Content.MimeType)
+		}
+		return
+	}
+	t.Error("success.har no contiene la página de la cuenta en ISO-8859-1")
+}
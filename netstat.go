@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// ErrNoInterface indica que no se encontró una interfaz de red que vigilar
+var ErrNoInterface = errors.New("no se encontró una interfaz de red activa (configure idle.interface)")
+
+// InterfaceCounters son los bytes recibidos y enviados por una interfaz desde
+// que el sistema la creó
+type InterfaceCounters struct {
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+}
+
+// Total devuelve los bytes recibidos y enviados
+func (c InterfaceCounters) Total() uint64 {
+	return c.RxBytes + c.TxBytes
+}
+
+// Sub devuelve el tráfico entre dos lecturas. Si los contadores se reiniciaron
+// (por ejemplo, al recrear la interfaz) devuelve false.
+func (c InterfaceCounters) Sub(previous InterfaceCounters) (InterfaceCounters, bool) {
+	if c.RxBytes < previous.RxBytes || c.TxBytes < previous.TxBytes {
+		return InterfaceCounters{}, false
+	}
+	return InterfaceCounters{
+		RxBytes: c.RxBytes - previous.RxBytes,
+		TxBytes: c.TxBytes - previous.TxBytes,
+	}, true
+}
+
+// vpnInterfacePrefixes son los prefijos de nombre de las interfaces creadas
+// por clientes VPN
+var vpnInterfacePrefixes = []string{"tun", "tap", "wg", "ppp", "nordlynx", "proton", "utun"}
+
+func isVPNInterface(name string) bool {
+	for _, prefix := range vpnInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isInterfaceUp indica si la interfaz está levantada y funcionando. Una
+// interfaz VPN puede seguir en /proc/net/dev después de desconectar.
+func isInterfaceUp(name string) bool {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return false
+	}
+	return iface.Flags&net.FlagUp != 0 && iface.Flags&net.FlagRunning != 0
+}
+
+// selectInterface elige la interfaz que se vigila: la configurada, la de la
+// VPN si preferVPN es true (la sesión conectó una) y está levantada, la Wi-Fi
+// o, en último caso, la primera con tráfico que no sea loopback ni VPN
+func selectInterface(configured string, preferVPN bool) (string, InterfaceCounters, error) {
+	counters, err := readInterfaceCounters()
+	if err != nil {
+		return "", InterfaceCounters{}, err
+	}
+
+	if configured != "" {
+		c, ok := counters[configured]
+		if !ok {
+			return "", InterfaceCounters{}, fmt.Errorf("la interfaz %s no existe", configured)
+		}
+		return configured, c, nil
+	}
+
+	names := make([]string, 0, len(counters))
+	for name := range counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	matchers := []func(string) bool{
+		isWirelessInterface,
+		func(name string) bool { return name != "lo" && !isVPNInterface(name) && counters[name].Total() > 0 },
+	}
+	if preferVPN {
+		activeVPN := func(name string) bool { return isVPNInterface(name) && isInterfaceUp(name) }
+		matchers = append([]func(string) bool{activeVPN}, matchers...)
+	}
+	for _, match := range matchers {
+		for _, name := range names {
+			if match(name) {
+				return name, counters[name], nil
+			}
+		}
+	}
+	return "", InterfaceCounters{}, ErrNoInterface
+}
@@ -0,0 +1,56 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const procNetDev = "/proc/net/dev"
+
+// readInterfaceCounters lee los contadores de todas las interfaces de
+// /proc/net/dev
+func readInterfaceCounters() (map[string]InterfaceCounters, error) {
+	file, err := os.Open(procNetDev)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	counters := make(map[string]InterfaceCounters)
+	scanner := bufio.NewScanner(file)
+	for line := 0; scanner.Scan(); line++ {
+		// Las dos primeras líneas son la cabecera
+		if line < 2 {
+			continue
+		}
+		name, data, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(data)
+		if len(fields) < 9 {
+			return nil, fmt.Errorf("%s: línea con formato desconocido: %q", procNetDev, scanner.Text())
+		}
+		rx, errRx := strconv.ParseUint(fields[0], 10, 64)
+		tx, errTx := strconv.ParseUint(fields[8], 10, 64)
+		if errRx != nil || errTx != nil {
+			return nil, fmt.Errorf("%s: contadores inválidos: %q", procNetDev, scanner.Text())
+		}
+		counters[strings.TrimSpace(name)] = InterfaceCounters{RxBytes: rx, TxBytes: tx}
+	}
+	return counters, scanner.Err()
+}
+
+// isWirelessInterface indica si la interfaz es Wi-Fi
+func isWirelessInterface(name string) bool {
+	if _, err := os.Stat(filepath.Join("/sys/class/net", name, "wireless")); err == nil {
+		return true
+	}
+	return strings.HasPrefix(name, "wl")
+}
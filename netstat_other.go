@@ -0,0 +1,15 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// readInterfaceCounters solo está disponible en Linux, donde se leen de
+// /proc/net/dev
+func readInterfaceCounters() (map[string]InterfaceCounters, error) {
+	return nil, errors.New("los contadores de tráfico solo están disponibles en Linux")
+}
+
+func isWirelessInterface(name string) bool {
+	return false
+}
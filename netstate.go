@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	eventSessionLost = "session_lost"
+
+	// staleSessionTicks es cuántos ciclos seguidos del daemon debe pedir el
+	// portal iniciar sesión para dar por terminada la sesión guardada
+	staleSessionTicks = 2
+
+	// sessionSeenInterval es cada cuánto registra el daemon que la sesión
+	// sigue abierta
+	sessionSeenInterval = 5 * time.Minute
+
+	// ProbeURL es una URL HTTP sin cifrar que responde 204 con acceso a
+	// internet. El portal cautivo de ETECSA la redirige a su página de login.
+	ProbeURL = "http://www.gstatic.com/generate_204"
+
+	// probeDisabled desactiva la detección del estado de la red
+	probeDisabled = "off"
+
+	// probeTimeout limita la espera al comprobar el estado de la red
+	probeTimeout = 10 * time.Second
+
+	// probeBodyLimit es cuánto se lee de una respuesta inesperada para buscar
+	// el portal en ella
+	probeBodyLimit = 64 * 1024
+)
+
+var (
+	// ErrNoNetwork indica que no hay red: el sistema no tiene ruta hacia
+	// ProbeURL
+	ErrNoNetwork = errors.New("no hay conexión de red")
+
+	// ErrProbeUnreachable indica que ProbeURL no respondió. La red de ETECSA
+	// sin sesión puede no resolver ni dejar conectar con servidores externos
+	// aunque el portal sí responda, así que no equivale a estar sin red.
+	ErrProbeUnreachable = errors.New("no se pudo alcanzar la URL de detección")
+
+	// ErrAlreadyOnline indica que ya hay acceso a internet sin pasar por el
+	// portal, por lo que no tiene sentido iniciar sesión
+	ErrAlreadyOnline = errors.New("ya hay acceso a internet: la red no es de ETECSA o ya hay una sesión abierta (quizás en otro equipo)")
+)
+
+// NetworkState es el estado de la red según la respuesta a ProbeURL
+type NetworkState int
+
+const (
+	// NetworkUnknown: la respuesta no permite saber el estado, por ejemplo
+	// otro portal cautivo, un fallo al resolver o conectar con ProbeURL o la
+	// detección desactivada
+	NetworkUnknown NetworkState = iota
+	// NetworkOffline: no hay red, el sistema no tiene ruta hacia ProbeURL
+	NetworkOffline
+	// NetworkPortal: el portal de ETECSA intercepta la petición, se está en
+	// su red sin sesión iniciada
+	NetworkPortal
+	// NetworkOnline: hay acceso a internet
+	NetworkOnline
+)
+
+// String describe el estado para mostrarlo al usuario
+func (s NetworkState) String() string {
+	switch s {
+	case NetworkOffline:
+		return "sin conexión de red"
+	case NetworkPortal:
+		return "en la red de ETECSA sin sesión iniciada"
+	case NetworkOnline:
+		return "con acceso a internet"
+	default:
+		return "desconocido"
+	}
+}
+
+// Key devuelve el nombre del estado para la salida JSON y los hooks
+func (s NetworkState) Key() string {
+	switch s {
+	case NetworkOffline:
+		return "offline"
+	case NetworkPortal:
+		return "portal"
+	case NetworkOnline:
+		return "online"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalJSON codifica el estado por su nombre
+func (s NetworkState) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + s.Key() + `"`), nil
+}
+
+// probeURL devuelve la URL de detección configurada o la predeterminada
+func (c *Config) probeURL() string {
+	if c.Portal.ProbeURL != "" {
+		return c.Portal.ProbeURL
+	}
+	return ProbeURL
+}
+
+// detectNetworkState pide ProbeURL sin seguir redirecciones y clasifica la
+// respuesta. El error explica los estados NetworkOffline y NetworkUnknown.
+func detectNetworkState(config *Config) (NetworkState, error) {
+	probe := config.probeURL()
+	if probe == probeDisabled {
+		return NetworkUnknown, errors.New("detección desactivada (portal.probe_url = off)")
+	}
+
+	portal, err := url.Parse(config.portalURL())
+	if err != nil {
+		return NetworkUnknown, fmt.Errorf("URL del portal inválida: %w", err)
+	}
+
+	transport, err := newTransport(config)
+	if err != nil {
+		return NetworkUnknown, err
+	}
+	client := &http.Client{
+		Timeout:   probeTimeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(probe)
+	if err != nil {
+		if errors.Is(err, syscall.ENETUNREACH) {
+			return NetworkOffline, fmt.Errorf("%w: %v", ErrNoNetwork, err)
+		}
+		return NetworkUnknown, fmt.Errorf("%w: %v", ErrProbeUnreachable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return NetworkOnline, nil
+
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		location, err := resp.Location()
+		if err != nil {
+			return NetworkUnknown, fmt.Errorf("redirección sin destino (HTTP %d)", resp.StatusCode)
+		}
+		if sameHost(location, portal) {
+			return NetworkPortal, nil
+		}
+		return NetworkUnknown, fmt.Errorf("redirección a %s, que no es el portal de ETECSA", location.Host)
+	}
+
+	// Algunos portales responden con una página que redirige con HTML o
+	// JavaScript en lugar de usar una redirección HTTP
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, probeBodyLimit))
+	if strings.Contains(string(body), portal.Hostname()) {
+		return NetworkPortal, nil
+	}
+	if resp.StatusCode == http.StatusOK && len(body) == 0 {
+		return NetworkOnline, nil
+	}
+	return NetworkUnknown, fmt.Errorf("respuesta inesperada (HTTP %d), quizás otro portal cautivo", resp.StatusCode)
+}
+
+// sameHost indica si dos URL apuntan al mismo servidor, sin tener en cuenta el
+// puerto
+func sameHost(a, b *url.URL) bool {
+	return strings.EqualFold(a.Hostname(), b.Hostname())
+}
+
+// checkNetwork da por terminada la sesión guardada del perfil cuando el portal
+// de ETECSA vuelve a pedir iniciar sesión, por ejemplo porque expiró o se
+// agotó el saldo
+func checkNetwork(d *daemon, config *Config, now time.Time) {
+	sessionData, err := LoadSession()
+	if err != nil {
+		return
+	}
+	// Con acceso a internet la sesión sigue abierta. Se registra para cerrar
+	// su historial en el último momento conocido si termina sin gonauta.
+	if d.network == NetworkOnline && d.due("session-seen", sessionSeenInterval, now) {
+		markSessionSeen(sessionData.RecordID, now)
+	}
+	if d.network != NetworkPortal || d.portalTicks < staleSessionTicks {
+		return
+	}
+
+	message := "La sesión ya no está activa en el portal de ETECSA (expiró o se agotó el saldo)"
+	d.logf("⚠️  %s", message)
+	endStaleSession(config, sessionData)
+	fireEvent(config, eventSessionLost, map[string]string{
+		"USERNAME": sessionData.Username,
+		"MESSAGE":  message,
+	})
+}
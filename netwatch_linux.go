@@ -0,0 +1,71 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// watchNetworkChanges avisa por changes cada vez que aparece una interfaz o
+// una dirección de red, suscribiéndose a los eventos de netlink. Termina al
+// cancelar ctx.
+func watchNetworkChanges(ctx context.Context, changes chan<- struct{}) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, unix.NETLINK_ROUTE)
+	if err != nil {
+		return err
+	}
+	defer unix.Close(fd)
+
+	addr := &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: unix.RTMGRP_LINK | unix.RTMGRP_IPV4_IFADDR | unix.RTMGRP_IPV6_IFADDR,
+	}
+	if err := unix.Bind(fd, addr); err != nil {
+		return err
+	}
+
+	// Despertar cada segundo para comprobar si se canceló ctx
+	timeout := unix.Timeval{Sec: 1}
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 64*1024)
+	for ctx.Err() == nil {
+		n, _, err := unix.Recvfrom(fd, buf, 0)
+		switch {
+		case errors.Is(err, unix.EAGAIN), errors.Is(err, unix.EINTR):
+			continue
+		case errors.Is(err, unix.ENOBUFS):
+			// Se perdieron eventos por desbordamiento: tratarlo como un cambio
+			notifyChange(changes)
+			continue
+		case err != nil:
+			return err
+		}
+
+		messages, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for _, message := range messages {
+			if message.Header.Type == unix.RTM_NEWLINK || message.Header.Type == unix.RTM_NEWADDR {
+				notifyChange(changes)
+			}
+		}
+	}
+	return nil
+}
+
+// notifyChange envía un aviso sin bloquear; si ya hay uno pendiente no hace
+// falta otro
+func notifyChange(changes chan<- struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
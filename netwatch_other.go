@@ -0,0 +1,13 @@
+//go:build !linux
+
+package main
+
+import (
+	"context"
+	"errors"
+)
+
+// watchNetworkChanges solo está disponible en Linux, donde usa netlink
+func watchNetworkChanges(ctx context.Context, changes chan<- struct{}) error {
+	return errors.New("la detección de cambios de red solo está disponible en Linux; use el script de NetworkManager u otro disparador que ejecute 'gonauta autologin run'")
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// maxDiagnosticPages es cuántas páginas no reconocidas se conservan en
+// ~/.gonauta/diagnostics
+const maxDiagnosticPages = 50
+
+// PageChangedError indica que una página del portal no tiene la forma
+// esperada, normalmente porque ETECSA la cambió
+type PageChangedError struct {
+	Page    string
+	Missing []string
+	Detail  string
+	Saved   string
+}
+
+func (e *PageChangedError) Error() string {
+	message := fmt.Sprintf("no se reconoce la página de %s", e.Page)
+	if len(e.Missing) > 0 {
+		message += ": falta " + strings.Join(e.Missing, ", ")
+	}
+	if e.Detail != "" {
+		message += ": " + e.Detail
+	}
+	if e.Saved != "" {
+		message += fmt.Sprintf(" (página guardada en %s)", e.Saved)
+	}
+	return message
+}
+
+// pageChanged guarda la página en el directorio de diagnóstico y devuelve el
+// error que describe lo que falta en ella
+func pageChanged(page, body string, missing []string, detail string) error {
+	err := &PageChangedError{Page: page, Missing: missing, Detail: detail}
+	if saved, saveErr := saveDiagnosticPage(page, body); saveErr == nil {
+		err.Saved = saved
+	}
+	return err
+}
+
+// getDiagnosticsDir devuelve el directorio donde se guardan las páginas no
+// reconocidas
+func getDiagnosticsDir() (string, error) {
+	baseDir, err := getBaseDir()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(baseDir, "diagnostics")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// saveDiagnosticPage guarda una página sin los UUID de sesión. Una misma
+// página solo se guarda una vez, y se conservan las maxDiagnosticPages más
+// recientes.
+func saveDiagnosticPage(page, body string) (string, error) {
+	dir, err := getDiagnosticsDir()
+	if err != nil {
+		return "", err
+	}
+
+	body = redactUUIDs(body)
+	sum := sha256.Sum256([]byte(body))
+	suffix := fmt.Sprintf("-%s-%s.html", diagnosticSlug(page), hex.EncodeToString(sum[:4]))
+	if existing, _ := filepath.Glob(filepath.Join(dir, "*"+suffix)); len(existing) > 0 {
+		return existing[0], nil
+	}
+
+	path := filepath.Join(dir, time.Now().Format("20060102-150405")+suffix)
+	if err := os.WriteFile(path, []byte(body), 0600); err != nil {
+		return "", err
+	}
+	pruneDiagnostics(dir)
+	return path, nil
+}
+
+// diagnosticSlug convierte el nombre de una página en parte de un nombre de
+// archivo
+func diagnosticSlug(page string) string {
+	return strings.ReplaceAll(normalizeText(page), " ", "-")
+}
+
+// pruneDiagnostics borra las páginas más antiguas. Los nombres empiezan por
+// la fecha, así que el orden alfabético es el cronológico.
+func pruneDiagnostics(dir string) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil || len(files) <= maxDiagnosticPages {
+		return
+	}
+	sort.Strings(files)
+	for _, file := range files[:len(files)-maxDiagnosticPages] {
+		os.Remove(file)
+	}
+}
+
+// accentReplacer quita los acentos de las vocales y la ñ
+var accentReplacer = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+)
+
+// normalizeText prepara un texto del portal para compararlo: minúsculas, sin
+// acentos, sin dos puntos finales y con los espacios colapsados
+func normalizeText(text string) string {
+	text = accentReplacer.Replace(strings.ToLower(text))
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.TrimSpace(strings.TrimRight(text, ":"))
+}
+
+// Formulario de login
+
+// loginFormSelectors son los formularios de login conocidos, del más reciente
+// al más antiguo
+var loginFormSelectors = []string{
+	"#formulario",
+	"form[action*='LoginServlet']",
+	"form:has(input[type='password'])",
+}
+
+// getLoginParams extrae los parámetros ocultos del formulario de login
+func (c *Client) getLoginParams(body string) (map[string]string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, selector := range loginFormSelectors {
+		form := doc.Find(selector).First()
+		if form.Length() == 0 {
+			continue
+		}
+		params := make(map[string]string)
+		form.Find("input[type='hidden']").Each(func(i int, s *goquery.Selection) {
+			if name, ok := s.Attr("name"); ok && name != "" {
+				value, _ := s.Attr("value")
+				params[name] = value
+			}
+		})
+		return params, nil
+	}
+
+	// Sin formulario la página suele estar incompleta: se reintenta
+	return nil, transient(pageChanged("login", body, []string{"el formulario de login (" + loginFormSelectors[0] + ")"}, ""))
+}
+
+// UUID de la sesión
+
+// uuidPatterns son las formas conocidas en que las páginas del portal incluyen
+// el UUID de la sesión, que es el primer grupo. Se usan tanto para extraerlo
+// como para ocultarlo en las trazas y las páginas de diagnóstico.
+var uuidPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`ATTRIBUTE_UUID=(\w+)`),
+	regexp.MustCompile(`name=["']?ATTRIBUTE_UUID["']?\s+value=["']?(\w+)`),
+}
+
+// redactUUIDs oculta en un texto los UUID de sesión que encuentra uuidPatterns
+func redactUUIDs(text string) string {
+	for _, pattern := range uuidPatterns {
+		matches := pattern.FindAllStringSubmatchIndex(text, -1)
+		for i := len(matches) - 1; i >= 0; i-- {
+			start, end := matches[i][2], matches[i][3]
+			text = text[:start] + redactedValue + text[end:]
+		}
+	}
+	return text
+}
+
+// extractUUID extrae el UUID de la respuesta del login
+func extractUUID(body string) (string, error) {
+	for _, pattern := range uuidPatterns {
+		if matches := pattern.FindStringSubmatch(body); len(matches) == 2 {
+			return matches[1], nil
+		}
+	}
+	return "", pageChanged("respuesta del login", body, []string{"el UUID de la sesión (ATTRIBUTE_UUID)"}, "")
+}
+
+// Información de la cuenta
+
+// userInfoField es un dato de la tabla de información de la cuenta
+type userInfoField struct {
+	name   string
+	labels []string
+}
+
+// userInfoFields son los datos de la cuenta y los fragmentos de sus etiquetas,
+// ya normalizados (ver normalizeText)
+var userInfoFields = []userInfoField{
+	{"Estado", []string{"estado"}},
+	{"Crédito", []string{"credito", "saldo"}},
+	{"Fecha de expiración", []string{"expiracion", "vencimiento"}},
+	{"Áreas de acceso", []string{"acceso"}},
+}
+
+// userInfoLayout es una versión conocida de la página de información
+type userInfoLayout struct {
+	name    string
+	extract func(doc *goquery.Document) map[string]string
+}
+
+// userInfoLayouts son las versiones conocidas de la página de información,
+// de la más fiable a la menos
+var userInfoLayouts = []userInfoLayout{
+	{"tabla #sessioninfo por etiquetas", func(doc *goquery.Document) map[string]string {
+		return labeledRows(doc.Find("#sessioninfo tr"))
+	}},
+	{"cualquier tabla por etiquetas", func(doc *goquery.Document) map[string]string {
+		return labeledRows(doc.Find("tr"))
+	}},
+	{"tabla #sessioninfo por posiciones", positionalRows},
+}
+
+// labeledRows busca en las filas de una tabla los datos de la cuenta por su
+// etiqueta, que es la primera celda; el valor es la última
+func labeledRows(rows *goquery.Selection) map[string]string {
+	values := make(map[string]string)
+	rows.Each(func(i int, row *goquery.Selection) {
+		cells := row.Find("th, td")
+		if cells.Length() < 2 {
+			return
+		}
+		label := normalizeText(cells.First().Text())
+		for _, field := range userInfoFields {
+			if _, found := values[field.name]; found {
+				continue
+			}
+			for _, fragment := range field.labels {
+				if strings.Contains(label, fragment) {
+					values[field.name] = strings.TrimSpace(cells.Last().Text())
+					return
+				}
+			}
+		}
+	})
+	return values
+}
+
+// positionalRows lee la tabla como la primera versión de la página: una fila
+// por dato, en el orden de userInfoFields
+func positionalRows(doc *goquery.Document) map[string]string {
+	values := make(map[string]string)
+	for i, field := range userInfoFields {
+		cell := doc.Find(fmt.Sprintf("#sessioninfo tr:nth-child(%d) td:nth-child(2)", i+1))
+		if cell.Length() > 0 {
+			values[field.name] = strings.TrimSpace(cell.Text())
+		}
+	}
+	return values
+}
+
+// missingFields devuelve los datos que faltan en values
+func missingFields(values map[string]string) []string {
+	var missing []string
+	for _, field := range userInfoFields {
+		if _, ok := values[field.name]; !ok {
+			missing = append(missing, fmt.Sprintf("«%s»", field.name))
+		}
+	}
+	return missing
+}
+
+// extractUserInfo extrae la información del usuario del HTML. Prueba las
+// versiones conocidas de la página y, si ninguna tiene todos los datos,
+// informa de los que faltan en la más completa.
+func extractUserInfo(body string) (*UserInfo, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	var best map[string]string
+	var bestLayout string
+	for _, layout := range userInfoLayouts {
+		values := layout.extract(doc)
+		if len(values) > len(best) {
+			best, bestLayout = values, layout.name
+		}
+		if len(values) == len(userInfoFields) {
+			break
+		}
+	}
+	if missing := missingFields(best); len(missing) > 0 {
+		detail := ""
+		if bestLayout != "" {
+			detail = "versión más parecida: " + bestLayout
+		}
+		return nil, pageChanged("información de la cuenta", body, missing, detail)
+	}
+
+	creditsText := strings.TrimSpace(strings.TrimSuffix(best["Crédito"], "CUP"))
+	if !strings.Contains(creditsText, ".") {
+		// Coma decimal
+		creditsText = strings.Replace(creditsText, ",", ".", 1)
+	}
+	credits, err := strconv.ParseFloat(creditsText, 64)
+	if err != nil {
+		return nil, pageChanged("información de la cuenta", body, nil, fmt.Sprintf("«Crédito» tiene un valor inesperado: %q", best["Crédito"]))
+	}
+
+	info := &UserInfo{
+		Status:     parseAccountStatus(best["Estado"]),
+		Credits:    credits,
+		AccessInfo: parseAccessInfo(best["Áreas de acceso"]),
+	}
+
+	// Una fecha que no se entiende no impide usar el resto de los datos: se
+	// conserva el texto del portal y quien la muestra lo advierte
+	info.ExpirationDate, err = parseExpirationDate(best["Fecha de expiración"])
+	if err != nil {
+		info.ExpirationText = strings.TrimSpace(best["Fecha de expiración"])
+	}
+	return info, nil
+}
+
+// noBalanceUserInfo devuelve la información de una cuenta sin saldo. Si la
+// página incluye la tabla de la cuenta se usan sus datos; si solo trae el
+// mensaje del portal, la cuenta se da por activa.
+func noBalanceUserInfo(body string) *UserInfo {
+	info := &UserInfo{Status: StatusActive, AccessInfo: parseAccessInfo("")}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body))
+	if err != nil {
+		return info
+	}
+	values := labeledRows(doc.Find("tr"))
+	if text, ok := values["Estado"]; ok {
+		info.Status = parseAccountStatus(text)
+	}
+	if text, ok := values["Fecha de expiración"]; ok {
+		info.ExpirationDate, _ = parseExpirationDate(text)
+	}
+	if text, ok := values["Áreas de acceso"]; ok {
+		info.AccessInfo = parseAccessInfo(text)
+	}
+	return info
+}
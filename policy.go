@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Policy es el formato de ~/.gonauta/policy.json, con las reglas comunes a
+// todos los perfiles
+type Policy struct {
+	Caps         SpendingCaps `json:"caps"`
+	AllowedHours []string     `json:"allowed_hours,omitempty"`
+}
+
+// getPolicyPath devuelve la ruta de la política compartida por todos los
+// perfiles
+func getPolicyPath() (string, error) {
+	baseDir, err := getBaseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(baseDir, "policy.json"), nil
+}
+
+// LoadPolicy devuelve la política global, o una vacía si no existe policy.json
+func LoadPolicy() (*Policy, error) {
+	policyPath, err := getPolicyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(policyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Policy{}, nil
+		}
+		return nil, err
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("%s: %w", policyPath, err)
+	}
+	if err := policy.Caps.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", policyPath, err)
+	}
+	if _, err := parseAllowedHours(policy.AllowedHours); err != nil {
+		return nil, fmt.Errorf("%s: %w", policyPath, err)
+	}
+	return &policy, nil
+}
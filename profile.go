@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+const defaultProfile = "default"
+
+// currentProfile es el perfil seleccionado con --profile o GONAUTA_PROFILE
+var currentProfile = defaultProfile
+
+var profileNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// setProfile selecciona el perfil activo
+func setProfile(name string) error {
+	if name == "" {
+		name = defaultProfile
+	}
+	if !profileNameRe.MatchString(name) {
+		return fmt.Errorf("nombre de perfil inválido: %q (use letras, números, '-' o '_')", name)
+	}
+	currentProfile = name
+	return nil
+}
+
+// getBaseDir devuelve el directorio raíz de configuración (~/.gonauta)
+func getBaseDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	baseDir := filepath.Join(homeDir, ".gonauta")
+	if err := os.MkdirAll(baseDir, 0700); err != nil {
+		return "", err
+	}
+	return baseDir, nil
+}
+
+// getProfileDirFor devuelve el directorio de un perfil. El perfil por defecto
+// usa directamente ~/.gonauta para mantener la compatibilidad.
+func getProfileDirFor(profile string) (string, error) {
+	baseDir, err := getBaseDir()
+	if err != nil {
+		return "", err
+	}
+	if profile == defaultProfile {
+		return baseDir, nil
+	}
+	profileDir := filepath.Join(baseDir, "profiles", profile)
+	if err := os.MkdirAll(profileDir, 0700); err != nil {
+		return "", err
+	}
+	return profileDir, nil
+}
+
+// getProfileDir devuelve el directorio del perfil activo
+func getProfileDir() (string, error) {
+	return getProfileDirFor(currentProfile)
+}
+
+// listProfiles devuelve los perfiles que tienen configuración guardada
+func listProfiles() ([]string, error) {
+	baseDir, err := getBaseDir()
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := []string{}
+	if _, err := os.Stat(filepath.Join(baseDir, "credentials.enc")); err == nil {
+		profiles = append(profiles, defaultProfile)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(baseDir, "profiles"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || !profileNameRe.MatchString(entry.Name()) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(baseDir, "profiles", entry.Name(), "credentials.enc")); err == nil {
+			profiles = append(profiles, entry.Name())
+		}
+	}
+
+	sort.Strings(profiles)
+	return profiles, nil
+}
+
+// withProfile ejecuta fn con otro perfil activo y después restaura el anterior
+func withProfile(profile string, fn func()) {
+	previous := currentProfile
+	currentProfile = profile
+	defer func() { currentProfile = previous }()
+	fn()
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// proxyDisabled desactiva el proxy aunque esté definido en el entorno
+const proxyDisabled = "off"
+
+// ErrProxyAuth indica que el proxy rechazó el usuario o la contraseña
+var ErrProxyAuth = errors.New("el proxy requiere autenticación o rechazó las credenciales (revise proxy.url)")
+
+// warnedProxyHosts son los hosts de dns.hosts de los que ya se avisó que se
+// alcanzan a través del proxy
+var warnedProxyHosts sync.Map
+
+// proxySchemes son los tipos de proxy admitidos: HTTP CONNECT y SOCKS5
+var proxySchemes = map[string]bool{
+	"http":    true,
+	"https":   true,
+	"socks5":  true,
+	"socks5h": true,
+}
+
+// parseProxyURL valida la URL de un proxy. Sin esquema se asume http, como
+// hacen curl y las variables de entorno.
+func parseProxyURL(value string) (*url.URL, error) {
+	if !strings.Contains(value, "://") {
+		value = "http://" + value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("proxy inválido: %s", value)
+	}
+	if !proxySchemes[u.Scheme] {
+		return nil, fmt.Errorf("tipo de proxy no soportado: %s (use http, https o socks5)", u.Scheme)
+	}
+	return u, nil
+}
+
+// getenvAny devuelve la primera variable de entorno definida, probando también
+// su nombre en minúsculas
+func getenvAny(names ...string) string {
+	for _, name := range names {
+		for _, variant := range []string{name, strings.ToLower(name)} {
+			if value := os.Getenv(variant); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// environmentProxy lee el proxy de HTTPS_PROXY, HTTP_PROXY y NO_PROXY.
+// ALL_PROXY se usa para las URL sin un proxy específico. Como en el resto de
+// programas, las direcciones locales nunca usan el proxy del entorno.
+func environmentProxy() func(*url.URL) (*url.URL, error) {
+	config := httpproxy.Config{
+		HTTPProxy:  getenvAny("HTTP_PROXY", "ALL_PROXY"),
+		HTTPSProxy: getenvAny("HTTPS_PROXY", "ALL_PROXY"),
+		NoProxy:    getenvAny("NO_PROXY"),
+	}
+	return config.ProxyFunc()
+}
+
+// proxyFunc devuelve cómo elegir el proxy de cada petición: el del perfil, el
+// del entorno si el perfil no define ninguno, o ninguno con "off"
+func (c *Config) proxyFunc() func(*http.Request) (*url.URL, error) {
+	var choose func(*url.URL) (*url.URL, error)
+	switch c.Proxy.URL {
+	case proxyDisabled:
+		return nil
+	case "":
+		choose = environmentProxy()
+	default:
+		proxy, err := parseProxyURL(c.Proxy.URL)
+		choose = func(*url.URL) (*url.URL, error) { return proxy, err }
+	}
+
+	return func(req *http.Request) (*url.URL, error) {
+		proxy, err := choose(req.URL)
+		if err != nil {
+			return nil, fmt.Errorf("configuración de proxy: %w", err)
+		}
+		if proxy != nil {
+			verbosef("%s a través del proxy %s", req.URL.Host, proxy.Redacted())
+			c.warnIgnoredHostOverride(req.URL.Hostname())
+		}
+		return proxy, nil
+	}
+}
+
+// warnIgnoredHostOverride avisa una vez si un host de dns.hosts se alcanza a
+// través del proxy, que resuelve el nombre por su cuenta
+func (c *Config) warnIgnoredHostOverride(host string) {
+	host = strings.ToLower(host)
+	if len(c.DNS.Hosts[host]) == 0 {
+		return
+	}
+	if _, warned := warnedProxyHosts.LoadOrStore(host, true); !warned {
+		fmt.Printf("Advertencia: %s se alcanza a través del proxy, que resuelve el nombre: dns.hosts no se aplica\n", host)
+	}
+}
+
+// checkProxyConnect detecta el rechazo de las credenciales en el CONNECT que
+// abre el túnel hacia un servidor HTTPS. Ese 407 no llega como respuesta sino
+// como error de conexión, así que checkStatus no lo ve.
+func checkProxyConnect(ctx context.Context, proxyURL *url.URL, connectReq *http.Request, connectRes *http.Response) error {
+	if connectRes.StatusCode == http.StatusProxyAuthRequired {
+		return ErrProxyAuth
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// replayOperations son las operaciones del cliente que 'gonauta replay' puede
+// ejecutar, en el orden en que se ejecutan por defecto
+var replayOperations = []string{"login", "info", "status", "logout"}
+
+// replayTransport responde a las peticiones con las respuestas grabadas en una
+// traza HAR, sin acceder a la red
+type replayTransport struct {
+	mu      sync.Mutex
+	entries []harEntry
+	used    []bool
+}
+
+func newReplayTransport(har *harFile) *replayTransport {
+	return &replayTransport{
+		entries: har.Log.Entries,
+		used:    make([]bool, len(har.Log.Entries)),
+	}
+}
+
+// replayPath normaliza la ruta de una URL para comparar peticiones
+func replayPath(u *url.URL) string {
+	if u.Path == "" {
+		return "/"
+	}
+	return u.Path
+}
+
+// requestFields devuelve los campos de la URL y del formulario de una petición
+func requestFields(req *http.Request) (url.Values, error) {
+	fields := req.URL.Query()
+	if req.Body == nil {
+		return fields, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return fields, nil
+	}
+	for name, values := range form {
+		fields[name] = append(fields[name], values...)
+	}
+	return fields, nil
+}
+
+// recordedFields devuelve los campos de la URL y del formulario de una petición
+// grabada
+func recordedFields(request harRequest) url.Values {
+	fields := url.Values{}
+	for _, pair := range request.QueryString {
+		fields.Add(pair.Name, pair.Value)
+	}
+	if request.PostData != nil {
+		for _, pair := range request.PostData.Params {
+			fields.Add(pair.Name, pair.Value)
+		}
+	}
+	return fields
+}
+
+// fieldsMatch indica si los campos grabados coinciden con los de la petición.
+// Los campos sensibles no se comparan porque la traza los oculta.
+func fieldsMatch(recorded, live url.Values) bool {
+	if len(recorded) != len(live) {
+		return false
+	}
+	for name, values := range recorded {
+		if sensitiveFields[name] {
+			if _, ok := live[name]; !ok {
+				return false
+			}
+			continue
+		}
+		if strings.Join(values, "\x00") != strings.Join(live[name], "\x00") {
+			return false
+		}
+	}
+	return true
+}
+
+// match busca la primera respuesta sin usar grabada para la petición. Si ya se
+// usaron todas, repite la última, para que las consultas repetidas funcionen.
+func (t *replayTransport) match(req *http.Request, fields url.Values) (*harEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last := -1
+	for i := range t.entries {
+		entry := &t.entries[i]
+		recordedURL, err := url.Parse(entry.Request.URL)
+		if err != nil || entry.Request.Method != req.Method || replayPath(recordedURL) != replayPath(req.URL) {
+			continue
+		}
+		if !fieldsMatch(recordedFields(entry.Request), fields) {
+			continue
+		}
+		if !t.used[i] {
+			t.used[i] = true
+			return entry, nil
+		}
+		last = i
+	}
+	if last >= 0 {
+		return &t.entries[last], nil
+	}
+	return nil, fmt.Errorf("la traza no tiene una respuesta para %s %s", req.Method, replayPath(req.URL))
+}
+
+// recorded indica si la traza tiene alguna petición a la ruta de rawURL
+func (t *replayTransport) recorded(rawURL string) bool {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	for _, entry := range t.entries {
+		if recordedURL, err := url.Parse(entry.Request.URL); err == nil && replayPath(recordedURL) == replayPath(target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fields, err := requestFields(req)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := t.match(req, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// Una petición que falló al grabarse falla igual al reproducirse
+	if entry.Response.Status == 0 {
+		return nil, fmt.Errorf("error grabado: %s", entry.Comment)
+	}
+
+	body, err := entry.Response.Content.body()
+	if err != nil {
+		return nil, fmt.Errorf("cuerpo grabado inválido: %w", err)
+	}
+	header := http.Header{}
+	for _, pair := range entry.Response.Headers {
+		if pair.Value == redactedValue {
+			continue
+		}
+		header.Add(pair.Name, pair.Value)
+	}
+	header.Del("Content-Length")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", entry.Response.Status, entry.Response.StatusText),
+		StatusCode:    entry.Response.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// recordedCredentials devuelve el usuario grabado en la traza. La contraseña
+// solo se recupera si la traza no está oculta.
+func recordedCredentials(har *harFile) (string, string) {
+	for _, entry := range har.Log.Entries {
+		fields := recordedFields(entry.Request)
+		if username := fields.Get("username"); username != "" && fields.Has("password") {
+			password := fields.Get("password")
+			if password == redactedValue {
+				password = ""
+			}
+			return username, password
+		}
+	}
+	return "", ""
+}
+
+// runReplayOperation ejecuta una operación del cliente y muestra su resultado.
+// session guarda la sesión obtenida con login para las operaciones siguientes.
+func runReplayOperation(client *Client, transport *replayTransport, operation, username, password string, session **SessionData) error {
+	switch operation {
+	case "login":
+		// Las trazas grabadas con el portal interceptando el tráfico no
+		// consultan la geolocalización antes del login
+		login := client.Login
+		if !transport.recorded(client.ipCheckURL) {
+			login = client.loginPortal
+		}
+		data, err := login(username, password)
+		if err != nil {
+			return err
+		}
+		*session = data
+		fmt.Printf("  UUID: %s\n", data.UUID)
+
+	case "info":
+		userInfo, err := client.GetUserInfo(username, password)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  Estado: %s\n", userInfo.Status)
+		fmt.Printf("  Saldo: %.2f CUP\n", userInfo.Credits)
+		if userInfo.ExpirationDate != nil {
+			fmt.Printf("  Expira: %s\n", userInfo.ExpirationDate.Format("2006-01-02"))
+		}
+
+	case "status", "logout":
+		if *session == nil {
+			return errors.New("no hay sesión: la traza debe incluir el inicio de sesión")
+		}
+		s := NewSession(**session, client)
+		if operation == "logout" {
+			return s.Logout()
+		}
+		remaining, err := s.GetRemainingTime()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  Tiempo restante: %s\n", formatDuration(remaining))
+
+	default:
+		return fmt.Errorf("operación desconocida: %s (use %s)", operation, strings.Join(replayOperations, ", "))
+	}
+	return nil
+}
+
+func handleReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	expectError := fs.String("expect-error", "", "las operaciones deben fallar con un error que contenga este texto")
+	parseCommandFlags(fs, args)
+	args = fs.Args()
+
+	if len(args) == 0 {
+		fmt.Println("Uso: gonauta replay [--expect-error <texto>] <traza.har> [login|info|status|logout ...]")
+		fmt.Println("\nEjecuta las operaciones del cliente con las respuestas grabadas con --trace,")
+		fmt.Println("sin acceder a la red ni modificar la sesión o el historial.")
+		os.Exit(2)
+	}
+
+	har, err := loadHAR(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	operations := args[1:]
+	if len(operations) == 0 {
+		operations = replayOperations
+	}
+
+	username, password := recordedCredentials(har)
+	if username == "" {
+		fmt.Println("Advertencia: La traza no incluye el usuario; se usa uno vacío")
+	}
+
+	// Configuración por defecto: la respuesta no depende del perfil activo y
+	// las peticiones se comparan solo por la ruta, no por el servidor
+	client, err := NewClient(&Config{})
+	if err != nil {
+		fmt.Printf("Error creando cliente: %v\n", err)
+		os.Exit(1)
+	}
+	transport := newReplayTransport(har)
+	client.httpClient.Transport = transport
+
+	failed := 0
+	var session *SessionData
+	for _, operation := range operations {
+		fmt.Printf("%s:\n", operation)
+		err := runReplayOperation(client, transport, operation, username, password, &session)
+		switch {
+		case *expectError == "" && err == nil:
+			fmt.Println("  ✓ Correcto")
+		case *expectError == "":
+			fmt.Printf("  ✗ %v\n", err)
+			failed++
+		case err == nil:
+			fmt.Printf("  ✗ Se esperaba un error con \"%s\"\n", *expectError)
+			failed++
+		case strings.Contains(err.Error(), *expectError):
+			fmt.Printf("  ✓ Error esperado: %v\n", err)
+		default:
+			fmt.Printf("  ✗ Error inesperado: %v\n", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		fmt.Printf("\n%d de %d operaciones fallaron\n", failed, len(operations))
+		os.Exit(1)
+	}
+}
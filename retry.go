@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	// defaultRetryAttempts y defaultRetryMaxTime limitan los reintentos si el
+	// perfil no define retry.max_attempts o retry.max_seconds
+	defaultRetryAttempts = 4
+	defaultRetryMaxTime  = time.Minute
+
+	// retryBaseDelay se duplica en cada intento hasta retryMaxDelay
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 15 * time.Second
+)
+
+// transientError marca un fallo pasajero del portal que puede reintentarse
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// transient marca un error como pasajero
+func transient(err error) error {
+	return &transientError{err: err}
+}
+
+// isTransient indica si un error puede desaparecer al reintentar: tiempos de
+// espera agotados, conexiones cortadas, errores 5xx y páginas incompletas.
+// Los errores del portal sobre la cuenta nunca son pasajeros, ni tampoco un
+// envío del login sin respuesta clara (ver loginPortal).
+func isTransient(err error) bool {
+	if errors.Is(err, ErrWrongPassword) || errors.Is(err, ErrNoBalance) ||
+		errors.Is(err, ErrAccountInUse) || errors.Is(err, ErrNotAuthorized) ||
+		errors.Is(err, ErrLoginUncertain) {
+		return false
+	}
+
+	var marked *transientError
+	if errors.As(err, &marked) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// checkStatus convierte las respuestas 5xx del portal en errores pasajeros.
+// Un 407 viene del proxy, no del portal, y no se reintenta.
+func checkStatus(resp *http.Response) error {
+	if resp.StatusCode == http.StatusProxyAuthRequired {
+		return ErrProxyAuth
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return transient(fmt.Errorf("el portal respondió con error HTTP %d", resp.StatusCode))
+	}
+	return nil
+}
+
+// RetryPolicy limita los reintentos de una operación con el portal
+type RetryPolicy struct {
+	MaxAttempts int
+	MaxTime     time.Duration
+}
+
+// retryPolicy devuelve la política de reintentos del perfil
+func (c *Config) retryPolicy() RetryPolicy {
+	policy := RetryPolicy{MaxAttempts: defaultRetryAttempts, MaxTime: defaultRetryMaxTime}
+	if c.Retry.MaxAttempts > 0 {
+		policy.MaxAttempts = c.Retry.MaxAttempts
+	}
+	if c.Retry.MaxSeconds > 0 {
+		policy.MaxTime = time.Duration(c.Retry.MaxSeconds) * time.Second
+	}
+	return policy
+}
+
+// delay devuelve la espera tras el intento fallido attempt: crece de forma
+// exponencial y se reparte al azar entre la mitad y el total, para que varios
+// clientes no reintenten a la vez
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	wait := retryMaxDelay
+	if attempt < 8 {
+		wait = min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	}
+	return wait/2 + rand.N(wait/2+1)
+}
+
+// do ejecuta fn hasta que termine bien, falle con un error que no es pasajero
+// o se agoten los intentos o el tiempo. El contexto de fn vence al agotarse
+// p.MaxTime, así que un intento lento tampoco lo supera. Devuelve el último
+// error.
+func (p RetryPolicy) do(operation string, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.MaxTime)
+	defer cancel()
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			if attempt > 1 {
+				verbosef("%s: completado en el intento %d", operation, attempt)
+			}
+			return nil
+		}
+		if !isTransient(err) {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			verbosef("%s: intento %d/%d fallido (%v); no quedan intentos", operation, attempt, p.MaxAttempts, err)
+			return err
+		}
+
+		wait := p.delay(attempt)
+		if time.Since(start)+wait > p.MaxTime {
+			verbosef("%s: intento %d/%d fallido (%v); se agotó el tiempo de reintentos", operation, attempt, p.MaxAttempts, err)
+			return err
+		}
+		verbosef("%s: intento %d/%d fallido (%v); reintentando en %s", operation, attempt, p.MaxAttempts, err, wait.Round(100*time.Millisecond))
+		time.Sleep(wait)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// runTimeoutExitCode es el código de salida cuando el comando supera
+	// --max-duration, igual que timeout(1)
+	runTimeoutExitCode = 124
+
+	// runKillDelay es cuánto se espera a que el comando termine tras pedirle
+	// que se detenga antes de matarlo
+	runKillDelay = 10 * time.Second
+)
+
+// sessionEnv devuelve las variables de entorno con la información de la sesión
+// para el comando ejecutado con 'gonauta run'
+func sessionEnv(session *SessionData) []string {
+	return append(os.Environ(),
+		"GONAUTA_PROFILE="+currentProfile,
+		"GONAUTA_USERNAME="+session.Username,
+		"GONAUTA_SESSION_ID="+session.RecordID,
+		"GONAUTA_STARTED_AT="+session.StartedAt.Format(time.RFC3339),
+	)
+}
+
+// runCommand ejecuta el comando hasta que termine o se cancele ctx, y devuelve
+// su código de salida
+func runCommand(ctx context.Context, session *SessionData, args []string) int {
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = sessionEnv(session)
+	cmd.Cancel = func() error {
+		// Pedir al comando que termine; si no lo hace, WaitDelay lo mata. En
+		// Windows no se puede enviar os.Interrupt y se mata directamente.
+		if err := cmd.Process.Signal(os.Interrupt); err != nil {
+			return cmd.Process.Kill()
+		}
+		return nil
+	}
+	cmd.WaitDelay = runKillDelay
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		fmt.Println("\n⚠️  El comando superó la duración máxima y se detuvo")
+		return runTimeoutExitCode
+	case errors.As(err, &exitErr):
+		// Un comando terminado por una señal sale como en la shell, con
+		// 128 + el número de la señal
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal())
+		}
+		if code := exitErr.ExitCode(); code > 0 {
+			return code
+		}
+		return 1
+	case err != nil:
+		fmt.Printf("Error ejecutando el comando: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func handleRun(args []string) {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	opts := addCredentialFlags(fs)
+	maxDuration := fs.Duration("max-duration", 0, "detener el comando y cerrar la sesión tras esta duración (ej: 30m)")
+	noVPN := fs.Bool("no-vpn", false, "no ejecutar el comando de conexión VPN")
+	force := fs.Bool("force", false, "conectar aunque se haya alcanzado un límite de gasto")
+	parseCommandFlags(fs, args)
+
+	command := fs.Args()
+	if len(command) == 0 {
+		fmt.Println("Uso: gonauta run [opciones] -- <comando> [argumentos]")
+		os.Exit(2)
+	}
+	if *maxDuration < 0 {
+		fmt.Println("Error: --max-duration no puede ser negativa")
+		os.Exit(2)
+	}
+
+	if existingSession, err := LoadSession(); err == nil && existingSession != nil {
+		fmt.Println("⚠️  Ya existe una sesión activa")
+		fmt.Println("Use 'gonauta logout' antes de usar 'gonauta run'")
+		os.Exit(1)
+	}
+
+	config, creds, err := resolveCredentials(*opts)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		fmt.Println("Use 'gonauta login' para guardar sus credenciales primero")
+		os.Exit(1)
+	}
+	if err := checkAllowedHours(config, time.Now()); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	forced := enforceCapsOnConnect(config, creds.Username, *force)
+
+	// Las señales recibidas a partir de aquí detienen el comando pero no a
+	// gonauta, que siempre cierra la sesión antes de salir
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	session, err := openSession(config, creds, connectOptions{skipVPN: *noVPN, forced: forced})
+	if err != nil {
+		fmt.Printf("Error al iniciar sesión: %v\n", err)
+		printSessionInUseHint(err)
+		os.Exit(1)
+	}
+
+	if *maxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *maxDuration)
+		defer cancel()
+	}
+
+	fmt.Printf("\nEjecutando: %s\n\n", strings.Join(command, " "))
+	exitCode := 1
+	if ctx.Err() == nil {
+		exitCode = runCommand(ctx, session, command)
+	}
+
+	fmt.Println()
+	if _, err := closeSession(config, session); err != nil {
+		fmt.Printf("⚠️  Error al cerrar sesión: %v\n", err)
+		fmt.Println("La sesión sigue abierta. Use 'gonauta logout' para cerrarla")
+		if exitCode == 0 {
+			exitCode = 1
+		}
+	}
+
+	stop()
+	os.Exit(exitCode)
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"testing"
+)
+
+func TestRunCommandExitCode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("usa sh y señales POSIX")
+	}
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"correcto", []string{"true"}, 0},
+		{"código de salida", []string{"sh", "-c", "exit 3"}, 3},
+		{"terminado por SIGTERM", []string{"sh", "-c", "kill -TERM $$"}, 128 + 15},
+		{"terminado por SIGKILL", []string{"sh", "-c", "kill -KILL $$"}, 128 + 9},
+		{"comando inexistente", []string{"gonauta-comando-inexistente"}, 1},
+	}
+	session := &SessionData{Username: testUsername}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runCommand(context.Background(), session, tt.args); got != tt.want {
+				t.Errorf("runCommand(%q) = %d, se esperaba %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	scheduleConnect    = "connect"
+	scheduleDisconnect = "disconnect"
+
+	// missedRunGrace es el retraso máximo con que el daemon ejecuta una tarea
+	// que no pudo ejecutarse a su hora (por ejemplo, si el equipo estaba
+	// suspendido). Las conexiones con duración se recuperan mientras dure su
+	// ventana.
+	missedRunGrace = 10 * time.Minute
+)
+
+// ScheduleEntry es una tarea programada de un perfil
+type ScheduleEntry struct {
+	ID        int        `json:"id"`
+	Action    string     `json:"action"`
+	Cron      string     `json:"cron"`
+	Duration  string     `json:"duration,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+}
+
+// duration devuelve cuánto debe durar la sesión abierta por la tarea, o cero
+// si no se cierra automáticamente
+func (e *ScheduleEntry) duration() time.Duration {
+	d, _ := time.ParseDuration(e.Duration)
+	return d
+}
+
+func (e *ScheduleEntry) validate() error {
+	if e.Action != scheduleConnect && e.Action != scheduleDisconnect {
+		return fmt.Errorf("acción inválida: %s (use connect o disconnect)", e.Action)
+	}
+	if _, err := parseCron(e.Cron); err != nil {
+		return err
+	}
+	if e.Duration != "" {
+		if e.Action != scheduleConnect {
+			return errors.New("solo las tareas connect pueden tener duración")
+		}
+		if d, err := time.ParseDuration(e.Duration); err != nil || d <= 0 {
+			return fmt.Errorf("duración inválida: %s (ej: 35m, 1h30m)", e.Duration)
+		}
+	}
+	return nil
+}
+
+// Schedule es el formato de schedule.json: las tareas del perfil y la sesión
+// abierta por una tarea que debe cerrarse
+type Schedule struct {
+	Entries       []ScheduleEntry `json:"entries"`
+	CloseAt       *time.Time      `json:"close_at,omitempty"`
+	CloseRecordID string          `json:"close_record_id,omitempty"`
+}
+
+func getSchedulePath() (string, error) {
+	profileDir, err := getProfileDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(profileDir, "schedule.json"), nil
+}
+
+// LoadSchedule devuelve las tareas programadas del perfil activo
+func LoadSchedule() (*Schedule, error) {
+	schedulePath, err := getSchedulePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(schedulePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Schedule{}, nil
+		}
+		return nil, err
+	}
+
+	var schedule Schedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return nil, fmt.Errorf("%s: %w", schedulePath, err)
+	}
+	return &schedule, nil
+}
+
+// SaveSchedule guarda las tareas programadas del perfil activo
+func SaveSchedule(schedule *Schedule) error {
+	schedulePath, err := getSchedulePath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(schedule, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(schedulePath, data, 0600)
+}
+
+// parseAllowedHours interpreta una lista de expresiones cron que indican los
+// minutos en que se permite conectar
+func parseAllowedHours(exprs []string) ([]*cronSchedule, error) {
+	schedules := make([]*cronSchedule, 0, len(exprs))
+	for _, expr := range exprs {
+		schedule, err := parseCron(expr)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+// allowedAt indica si alguna de las expresiones permite conectar en now. Una
+// lista vacía no impone restricciones.
+func allowedAt(exprs []string, now time.Time) (bool, error) {
+	if len(exprs) == 0 {
+		return true, nil
+	}
+	schedules, err := parseAllowedHours(exprs)
+	if err != nil {
+		return false, err
+	}
+	for _, schedule := range schedules {
+		if schedule.matches(now) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkAllowedHours devuelve un error si el perfil o la política global no
+// permiten conectar en now
+func checkAllowedHours(config *Config, now time.Time) error {
+	policy, err := LoadPolicy()
+	if err != nil {
+		return err
+	}
+
+	for _, rule := range []struct {
+		scope string
+		exprs []string
+	}{
+		{capScopeProfile, config.Schedule.AllowedHours},
+		{capScopeGlobal, policy.AllowedHours},
+	} {
+		allowed, err := allowedAt(rule.exprs, now)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("no se permite conectar a esta hora (horario %s: %s)",
+				rule.scope, strings.Join(rule.exprs, "; "))
+		}
+	}
+	return nil
+}
+
+// checkSchedule ejecuta las tareas programadas del perfil que correspondan y
+// cierra las sesiones abiertas por una tarea al terminar su ventana
+func checkSchedule(d *daemon, config *Config, now time.Time) {
+	schedule, err := LoadSchedule()
+	if err != nil {
+		d.logf("Error leyendo las tareas programadas: %v", err)
+		return
+	}
+	if len(schedule.Entries) == 0 && schedule.CloseAt == nil {
+		return
+	}
+
+	if schedule.CloseAt != nil && !now.Before(*schedule.CloseAt) {
+		if sessionData, err := LoadSession(); err == nil && sessionData.RecordID == schedule.CloseRecordID {
+			d.logf("Fin de la ventana programada: cerrando la sesión")
+			if _, err := closeSession(config, sessionData); err != nil {
+				d.logf("Error cerrando la sesión: %v", err)
+			}
+		}
+		schedule.CloseAt = nil
+		schedule.CloseRecordID = ""
+	}
+
+	for i := range schedule.Entries {
+		entry := &schedule.Entries[i]
+		run, ok := entry.dueRun(now)
+		if !ok {
+			continue
+		}
+		entry.LastRun = &now
+		if run.IsZero() {
+			d.logf("Se omitió la tarea #%d (%s %s): el daemon no estaba activo a su hora", entry.ID, entry.Action, entry.Cron)
+			continue
+		}
+		d.runScheduled(config, schedule, entry, run, now)
+	}
+
+	if err := SaveSchedule(schedule); err != nil {
+		d.logf("Error guardando las tareas programadas: %v", err)
+	}
+}
+
+// dueRun indica si la tarea tiene una ejecución pendiente. Devuelve la más
+// reciente que todavía puede recuperarse, o el instante cero si todas las
+// pendientes se perdieron.
+func (e *ScheduleEntry) dueRun(now time.Time) (time.Time, bool) {
+	schedule, err := parseCron(e.Cron)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	from := e.CreatedAt
+	if e.LastRun != nil {
+		from = *e.LastRun
+	}
+	due := schedule.next(from)
+	if due.IsZero() || due.After(now) {
+		return time.Time{}, false
+	}
+
+	window := missedRunGrace
+	if d := e.duration(); d > window {
+		window = d
+	}
+	start := due
+	if earliest := now.Add(-window); start.Before(earliest) {
+		start = schedule.next(earliest.Add(-time.Minute))
+	}
+
+	var run time.Time
+	for t := start; !t.IsZero() && !t.After(now); t = schedule.next(t) {
+		run = t
+	}
+	return run, true
+}
+
+// runScheduled ejecuta una tarea programada para el instante run
+func (d *daemon) runScheduled(config *Config, schedule *Schedule, entry *ScheduleEntry, run, now time.Time) {
+	sessionData, sessionErr := LoadSession()
+
+	switch entry.Action {
+	case scheduleDisconnect:
+		if sessionErr != nil {
+			return
+		}
+		d.logf("Tarea #%d: cerrando la sesión", entry.ID)
+		if _, err := closeSession(config, sessionData); err != nil {
+			d.logf("Error cerrando la sesión: %v", err)
+		}
+
+	case scheduleConnect:
+		if sessionErr == nil {
+			d.logf("Tarea #%d: ya hay una sesión activa", entry.ID)
+			return
+		}
+		if err := checkAllowedHours(config, now); err != nil {
+			d.logf("Tarea #%d: %v", entry.ID, err)
+			return
+		}
+		if reached, block, err := reachedCaps(config, now); err == nil && block {
+			d.logf("Tarea #%d: no se conecta: %s", entry.ID, reached[0])
+			return
+		}
+		creds, err := resolveUnattendedCredentials(config)
+		if err != nil {
+			d.logf("Tarea #%d: %v", entry.ID, err)
+			return
+		}
+
+		d.logf("Tarea #%d: iniciando sesión", entry.ID)
+		session, err := openSession(config, creds, connectOptions{})
+		if err != nil {
+			d.logf("Error al iniciar sesión: %v", err)
+			return
+		}
+		if duration := entry.duration(); duration > 0 && session.RecordID != "" {
+			closeAt := run.Add(duration)
+			schedule.CloseAt = &closeAt
+			schedule.CloseRecordID = session.RecordID
+			d.logf("La sesión se cerrará a las %s", closeAt.Format("15:04"))
+		}
+	}
+}
+
+func printScheduleUsage() {
+	fmt.Println("Uso: gonauta schedule <subcomando>")
+	fmt.Println("\nSubcomandos:")
+	fmt.Println("  list                                     Ver las tareas programadas del perfil")
+	fmt.Println("  add [--for <duración>] <acción> <cron>   Programar connect o disconnect")
+	fmt.Println("  remove <id>                              Eliminar una tarea")
+	fmt.Println("\nLa expresión cron tiene 5 campos: minuto hora día mes día-semana.")
+	fmt.Println("Las tareas las ejecuta 'gonauta daemon'.")
+	fmt.Println("\nEjemplos:")
+	fmt.Println("  gonauta schedule add --for 35m connect \"55 7 * * 1-5\"")
+	fmt.Println("  gonauta schedule add disconnect \"0 23 * * *\"")
+}
+
+func handleSchedule(args []string) {
+	if len(args) == 0 {
+		printScheduleUsage()
+		os.Exit(1)
+	}
+
+	schedule, err := LoadSchedule()
+	if err != nil {
+		fmt.Printf("Error leyendo las tareas programadas: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		listSchedule(schedule)
+
+	case "add":
+		fs := flag.NewFlagSet("schedule add", flag.ContinueOnError)
+		duration := fs.String("for", "", "cerrar la sesión después de esta duración (solo connect)")
+		parseCommandFlags(fs, args[1:])
+		if fs.NArg() < 2 {
+			printScheduleUsage()
+			os.Exit(1)
+		}
+
+		entry := ScheduleEntry{
+			ID:        nextScheduleID(schedule),
+			Action:    fs.Arg(0),
+			Cron:      strings.Join(fs.Args()[1:], " "),
+			Duration:  *duration,
+			CreatedAt: time.Now(),
+		}
+		if err := entry.validate(); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		schedule.Entries = append(schedule.Entries, entry)
+		if err := SaveSchedule(schedule); err != nil {
+			fmt.Printf("Error guardando las tareas programadas: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Tarea #%d programada\n", entry.ID)
+
+	case "remove":
+		if len(args) != 2 {
+			printScheduleUsage()
+			os.Exit(1)
+		}
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Printf("Error: id inválido: %s\n", args[1])
+			os.Exit(1)
+		}
+
+		entries := schedule.Entries[:0]
+		for _, entry := range schedule.Entries {
+			if entry.ID != id {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) == len(schedule.Entries) {
+			fmt.Printf("Error: no existe la tarea #%d\n", id)
+			os.Exit(1)
+		}
+		schedule.Entries = entries
+		if err := SaveSchedule(schedule); err != nil {
+			fmt.Printf("Error guardando las tareas programadas: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Tarea #%d eliminada\n", id)
+
+	case "help", "-h", "--help":
+		printScheduleUsage()
+
+	default:
+		fmt.Printf("Subcomando desconocido: %s\n\n", args[0])
+		printScheduleUsage()
+		os.Exit(1)
+	}
+}
+
+func nextScheduleID(schedule *Schedule) int {
+	id := 1
+	for _, entry := range schedule.Entries {
+		if entry.ID >= id {
+			id = entry.ID + 1
+		}
+	}
+	return id
+}
+
+func listSchedule(schedule *Schedule) {
+	if len(schedule.Entries) == 0 {
+		fmt.Println("No hay tareas programadas")
+		fmt.Println("Use 'gonauta schedule add' para programar una")
+		return
+	}
+
+	now := time.Now()
+	fmt.Printf("%-4s  %-10s  %-18s  %-8s  %-16s  %s\n", "ID", "Acción", "Cron", "Duración", "Próxima", "Última")
+	for _, entry := range schedule.Entries {
+		next, last := "-", "-"
+		if cron, err := parseCron(entry.Cron); err == nil {
+			if t := cron.next(now); !t.IsZero() {
+				next = t.Format("2006-01-02 15:04")
+			}
+		}
+		if entry.LastRun != nil {
+			last = entry.LastRun.Format("2006-01-02 15:04")
+		}
+		duration := entry.Duration
+		if duration == "" {
+			duration = "-"
+		}
+		fmt.Printf("%-4d  %-10s  %-18s  %-8s  %-16s  %s\n", entry.ID, entry.Action, entry.Cron, duration, next, last)
+	}
+	if schedule.CloseAt != nil {
+		fmt.Printf("\nLa sesión abierta por una tarea se cerrará el %s\n", schedule.CloseAt.Format("2006-01-02 15:04"))
+	}
+}
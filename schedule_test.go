@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleEntryDueRun(t *testing.T) {
+	created := cronTime(2026, 10, 18, 0, 0)
+	lastRun := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		name    string
+		entry   ScheduleEntry
+		now     time.Time
+		wantRun time.Time
+		wantDue bool
+	}{
+		{
+			name:  "antes de la hora",
+			entry: ScheduleEntry{Cron: "0 8 * * *", CreatedAt: created, LastRun: lastRun(cronTime(2026, 10, 18, 8, 0))},
+			now:   cronTime(2026, 10, 19, 7, 59),
+		},
+		{
+			name:    "a la hora",
+			entry:   ScheduleEntry{Cron: "0 8 * * *", CreatedAt: created, LastRun: lastRun(cronTime(2026, 10, 18, 8, 0))},
+			now:     cronTime(2026, 10, 19, 8, 0),
+			wantRun: cronTime(2026, 10, 19, 8, 0),
+			wantDue: true,
+		},
+		{
+			name:    "primera ejecución desde la creación",
+			entry:   ScheduleEntry{Cron: "0 8 * * *", CreatedAt: created},
+			now:     cronTime(2026, 10, 18, 8, 0),
+			wantRun: cronTime(2026, 10, 18, 8, 0),
+			wantDue: true,
+		},
+		{
+			name:    "con retraso dentro del margen",
+			entry:   ScheduleEntry{Cron: "0 8 * * *", CreatedAt: created, LastRun: lastRun(cronTime(2026, 10, 18, 8, 0))},
+			now:     cronTime(2026, 10, 19, 8, 9),
+			wantRun: cronTime(2026, 10, 19, 8, 0),
+			wantDue: true,
+		},
+		{
+			name:    "perdida fuera del margen",
+			entry:   ScheduleEntry{Cron: "0 8 * * *", CreatedAt: created, LastRun: lastRun(cronTime(2026, 10, 18, 8, 0))},
+			now:     cronTime(2026, 10, 19, 8, 30),
+			wantDue: true,
+		},
+		{
+			name:    "recuperada durante su duración",
+			entry:   ScheduleEntry{Action: scheduleConnect, Cron: "0 8 * * *", Duration: "1h", CreatedAt: created, LastRun: lastRun(cronTime(2026, 10, 18, 8, 0))},
+			now:     cronTime(2026, 10, 19, 8, 30),
+			wantRun: cronTime(2026, 10, 19, 8, 0),
+			wantDue: true,
+		},
+		{
+			name:    "varias pendientes: solo la más reciente",
+			entry:   ScheduleEntry{Cron: "*/5 * * * *", CreatedAt: created, LastRun: lastRun(cronTime(2026, 10, 19, 8, 0))},
+			now:     cronTime(2026, 10, 19, 8, 17),
+			wantRun: cronTime(2026, 10, 19, 8, 15),
+			wantDue: true,
+		},
+		{
+			name:    "daemon parado varios días",
+			entry:   ScheduleEntry{Cron: "0 8 * * *", CreatedAt: created, LastRun: lastRun(cronTime(2026, 10, 10, 8, 0))},
+			now:     cronTime(2026, 10, 19, 8, 5),
+			wantRun: cronTime(2026, 10, 19, 8, 0),
+			wantDue: true,
+		},
+		{
+			name:    "pendiente del mes anterior",
+			entry:   ScheduleEntry{Cron: "55 23 31 * *", CreatedAt: cronTime(2026, 10, 1, 0, 0)},
+			now:     cronTime(2026, 11, 1, 0, 2),
+			wantRun: cronTime(2026, 10, 31, 23, 55),
+			wantDue: true,
+		},
+		{
+			name:    "pendiente del año anterior",
+			entry:   ScheduleEntry{Cron: "59 23 * * *", CreatedAt: created, LastRun: lastRun(cronTime(2026, 12, 30, 23, 59))},
+			now:     cronTime(2027, 1, 1, 0, 3),
+			wantRun: cronTime(2026, 12, 31, 23, 59),
+			wantDue: true,
+		},
+		{
+			name:  "expresión inválida",
+			entry: ScheduleEntry{Cron: "0 25 * * *", CreatedAt: created},
+			now:   cronTime(2026, 10, 19, 8, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run, due := tt.entry.dueRun(tt.now)
+			if due != tt.wantDue || !run.Equal(tt.wantRun) {
+				t.Errorf("dueRun(%s) = %s, %v; se esperaba %s, %v", tt.now, run, due, tt.wantRun, tt.wantDue)
+			}
+		})
+	}
+}
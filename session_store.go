@@ -3,20 +3,30 @@ package main
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 )
 
+// ErrNoSession indica que no hay ninguna sesión guardada
+var ErrNoSession = errors.New("no hay sesión activa")
+
 func getSessionPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	profileDir, err := getProfileDir()
 	if err != nil {
 		return "", err
 	}
-	configDir := filepath.Join(homeDir, ".gonauta")
-	if err := os.MkdirAll(configDir, 0700); err != nil {
+	return filepath.Join(profileDir, "session.enc"), nil
+}
+
+// getLegacySessionPath devuelve la ruta del archivo de sesión en texto plano
+// usado por versiones anteriores
+func getLegacySessionPath() (string, error) {
+	profileDir, err := getProfileDir()
+	if err != nil {
 		return "", err
 	}
-	return filepath.Join(configDir, "session.json"), nil
+	return filepath.Join(profileDir, "session.json"), nil
 }
 
 func SaveSession(session *SessionData) error {
@@ -25,12 +35,17 @@ func SaveSession(session *SessionData) error {
 		return err
 	}
 
+	encrypted, err := sealContainer(purposeSession, data, getEncryptionKey())
+	if err != nil {
+		return err
+	}
+
 	sessionPath, err := getSessionPath()
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(sessionPath, data, 0600)
+	return os.WriteFile(sessionPath, encrypted, 0600)
 }
 
 func LoadSession() (*SessionData, error) {
@@ -39,16 +54,52 @@ func LoadSession() (*SessionData, error) {
 		return nil, err
 	}
 
-	data, err := os.ReadFile(sessionPath)
+	encrypted, err := os.ReadFile(sessionPath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, errors.New("no hay sesión activa")
+			return loadLegacySession()
 		}
 		return nil, err
 	}
 
+	data, err := openContainer(purposeSession, encrypted, getEncryptionKey())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", sessionPath, err)
+	}
+
 	var sessionData SessionData
 	if err := json.Unmarshal(data, &sessionData); err != nil {
+		return nil, fmt.Errorf("%s: %w: contenido inválido", sessionPath, ErrCorruptFile)
+	}
+
+	return &sessionData, nil
+}
+
+// loadLegacySession lee un session.json en texto plano, lo vuelve a guardar
+// cifrado y elimina el original
+func loadLegacySession() (*SessionData, error) {
+	legacyPath, err := getLegacySessionPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(legacyPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoSession
+		}
+		return nil, err
+	}
+
+	var sessionData SessionData
+	if err := json.Unmarshal(data, &sessionData); err != nil {
+		return nil, fmt.Errorf("%s: %w: contenido inválido", legacyPath, ErrCorruptFile)
+	}
+
+	if err := SaveSession(&sessionData); err != nil {
+		return nil, err
+	}
+	if err := os.Remove(legacyPath); err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
 
@@ -60,11 +111,17 @@ func DeleteSession() error {
 	if err != nil {
 		return err
 	}
-
-	err = os.Remove(sessionPath)
-	if err != nil && !os.IsNotExist(err) {
+	legacyPath, err := getLegacySessionPath()
+	if err != nil {
 		return err
 	}
 
+	for _, path := range []string{sessionPath, legacyPath} {
+		err = os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,564 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// configKey describe una opción editable con 'gonauta config'
+type configKey struct {
+	name        string
+	description string
+	get         func(c *Config) string
+	set         func(c *Config, value string) error
+}
+
+// configKeys contiene las opciones editables. La contraseña no está incluida
+// a propósito: solo se cambia con 'gonauta login'.
+var configKeys = []configKey{
+	{
+		name:        "username",
+		description: "Usuario de Nauta",
+		get:         func(c *Config) string { return c.Username },
+		set: func(c *Config, value string) error {
+			if value == "" {
+				return errors.New("el usuario no puede estar vacío")
+			}
+			c.Username = value
+			return nil
+		},
+	},
+	{
+		name:        "credentials.sources",
+		description: "Fuentes de credenciales separadas por comas (env, command, password-file, stdin, file)",
+		get:         func(c *Config) string { return strings.Join(c.Credentials.Sources, ",") },
+		set: func(c *Config, value string) error {
+			sources, err := parseCredentialSources(value)
+			if err != nil {
+				return err
+			}
+			c.Credentials.Sources = sources
+			return nil
+		},
+	},
+	{
+		name:        "credentials.password_command",
+		description: "Comando que imprime la contraseña (ej: pass show nauta)",
+		get:         func(c *Config) string { return c.Credentials.PasswordCommand },
+		set:         func(c *Config, value string) error { c.Credentials.PasswordCommand = value; return nil },
+	},
+	{
+		name:        "credentials.password_file",
+		description: "Archivo que contiene la contraseña",
+		get:         func(c *Config) string { return c.Credentials.PasswordFile },
+		set:         func(c *Config, value string) error { c.Credentials.PasswordFile = value; return nil },
+	},
+	{
+		name:        "vpn.connect_cmd",
+		description: "Comando ejecutado después de conectar",
+		get:         func(c *Config) string { return c.VPN.ConnectCmd },
+		set:         func(c *Config, value string) error { c.VPN.ConnectCmd = value; return nil },
+	},
+	{
+		name:        "vpn.disconnect_cmd",
+		description: "Comando ejecutado antes de cerrar sesión",
+		get:         func(c *Config) string { return c.VPN.DisconnectCmd },
+		set:         func(c *Config, value string) error { c.VPN.DisconnectCmd = value; return nil },
+	},
+	{
+		name:        "portal.url",
+		description: "URL del portal cautivo (por defecto " + BaseURL + ")",
+		get:         func(c *Config) string { return c.Portal.URL },
+		set: func(c *Config, value string) error {
+			if err := validateURL(value); err != nil {
+				return err
+			}
+			c.Portal.URL = value
+			return nil
+		},
+	},
+	{
+		name:        "portal.ip_check_url",
+		description: "URL del servicio de geolocalización IP (por defecto " + IPCheckURL + ")",
+		get:         func(c *Config) string { return c.Portal.IPCheckURL },
+		set: func(c *Config, value string) error {
+			if err := validateURL(value); err != nil {
+				return err
+			}
+			c.Portal.IPCheckURL = value
+			return nil
+		},
+	},
+	{
+		name:        "portal.probe_url",
+		description: "URL HTTP usada para detectar el estado de la red, u off para no detectarlo (por defecto " + ProbeURL + ")",
+		get:         func(c *Config) string { return c.Portal.ProbeURL },
+		set: func(c *Config, value string) error {
+			if value != probeDisabled {
+				if err := validateURL(value); err != nil {
+					return err
+				}
+				if strings.HasPrefix(value, "https:") {
+					return fmt.Errorf("use una URL http: el portal no puede interceptar las peticiones https")
+				}
+			}
+			c.Portal.ProbeURL = value
+			return nil
+		},
+	},
+	{
+		name:        "retry.max_attempts",
+		description: "Intentos ante fallos pasajeros del portal, 1 para no reintentar (por defecto 4)",
+		get:         func(c *Config) string { return formatIntSetting(c.Retry.MaxAttempts) },
+		set: func(c *Config, value string) error {
+			attempts, err := parseIntSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Retry.MaxAttempts = attempts
+			return nil
+		},
+	},
+	{
+		name:        "retry.max_seconds",
+		description: "Tiempo máximo en segundos dedicado a reintentar (por defecto 60)",
+		get:         func(c *Config) string { return formatIntSetting(c.Retry.MaxSeconds) },
+		set: func(c *Config, value string) error {
+			seconds, err := parseIntSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Retry.MaxSeconds = seconds
+			return nil
+		},
+	},
+	{
+		name:        "proxy.url",
+		description: "Proxy para el portal: http://[usuario:clave@]host:puerto o socks5://..., off para no usarlo (por defecto HTTPS_PROXY/ALL_PROXY)",
+		get: func(c *Config) string {
+			// No mostrar la contraseña del proxy
+			if c.Proxy.URL == "" || c.Proxy.URL == proxyDisabled {
+				return c.Proxy.URL
+			}
+			if proxy, err := parseProxyURL(c.Proxy.URL); err == nil {
+				return proxy.Redacted()
+			}
+			return c.Proxy.URL
+		},
+		set: func(c *Config, value string) error {
+			if value != "" && value != proxyDisabled {
+				if _, err := parseProxyURL(value); err != nil {
+					return err
+				}
+			}
+			c.Proxy.URL = value
+			return nil
+		},
+	},
+	{
+		name:        "tls.ca_file",
+		description: "Archivo PEM con CA adicionales en las que confiar para el portal",
+		get:         func(c *Config) string { return c.TLS.CAFile },
+		set: func(c *Config, value string) error {
+			if value != "" {
+				if _, err := loadCAFile(value); err != nil {
+					return err
+				}
+			}
+			c.TLS.CAFile = value
+			return nil
+		},
+	},
+	{
+		name:        "tls.pins",
+		description: "Pines sha256/<base64> de la clave pública del certificado del portal, separados por comas (ver 'gonauta tls')",
+		get:         func(c *Config) string { return strings.Join(c.TLS.Pins, ",") },
+		set: func(c *Config, value string) error {
+			pins, err := parsePins(value)
+			if err != nil {
+				return err
+			}
+			c.TLS.Pins = pins
+			return nil
+		},
+	},
+	{
+		name:        "tls.pin_mode",
+		description: "Qué hacer si el certificado no coincide con los pines: enforce (rechazarlo) o warn (avisar) (por defecto enforce)",
+		get:         func(c *Config) string { return c.TLS.PinMode },
+		set: func(c *Config, value string) error {
+			if value != "" && value != pinModeEnforce && value != pinModeWarn {
+				return fmt.Errorf("modo inválido: %s (use %s o %s)", value, pinModeEnforce, pinModeWarn)
+			}
+			c.TLS.PinMode = value
+			return nil
+		},
+	},
+	{
+		name:        "dns.hosts",
+		description: "Direcciones fijas para nombres del portal, como host=ip separados por comas (ej: secure.etecsa.net=10.180.0.30)",
+		get:         func(c *Config) string { return formatHostOverrides(c.DNS.Hosts) },
+		set: func(c *Config, value string) error {
+			hosts, err := parseHostOverrides(value)
+			if err != nil {
+				return err
+			}
+			c.DNS.Hosts = hosts
+			return nil
+		},
+	},
+	{
+		name:        "dns.server",
+		description: "Servidor DNS (ip[:puerto]) para resolver los nombres que no están en dns.hosts (por defecto el del sistema)",
+		get:         func(c *Config) string { return c.DNS.Server },
+		set: func(c *Config, value string) error {
+			if value != "" {
+				if _, err := parseDNSServer(value); err != nil {
+					return err
+				}
+			}
+			c.DNS.Server = value
+			return nil
+		},
+	},
+	{
+		name:        "thresholds.low_balance",
+		description: "Avisar cuando el saldo sea menor que esta cantidad de CUP",
+		get:         func(c *Config) string { return formatFloatSetting(c.Thresholds.LowBalance) },
+		set: func(c *Config, value string) error {
+			amount, err := parseFloatSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Thresholds.LowBalance = amount
+			return nil
+		},
+	},
+	{
+		name:        "thresholds.low_time_minutes",
+		description: "Avisar cuando el tiempo restante de la sesión sea menor que estos minutos",
+		get:         func(c *Config) string { return formatIntSetting(c.Thresholds.LowTimeMinutes) },
+		set: func(c *Config, value string) error {
+			minutes, err := parseIntSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Thresholds.LowTimeMinutes = minutes
+			return nil
+		},
+	},
+	{
+		name:        "thresholds.expiry_days",
+		description: "Avisar cuando falten menos de estos días para que expire la cuenta (por defecto 7)",
+		get:         func(c *Config) string { return formatIntSetting(c.Thresholds.ExpiryDays) },
+		set: func(c *Config, value string) error {
+			days, err := parseIntSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Thresholds.ExpiryDays = days
+			return nil
+		},
+	},
+	{
+		name:        "thresholds.forecast_days",
+		description: "Avisar cuando el saldo se agote en menos de estos días al ritmo de gasto actual (por defecto 3)",
+		get:         func(c *Config) string { return formatIntSetting(c.Thresholds.ForecastDays) },
+		set: func(c *Config, value string) error {
+			days, err := parseIntSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Thresholds.ForecastDays = days
+			return nil
+		},
+	},
+	{
+		name:        "hooks.post_connect",
+		description: "Comando ejecutado después de iniciar sesión",
+		get:         func(c *Config) string { return c.Hooks.PostConnect },
+		set:         func(c *Config, value string) error { c.Hooks.PostConnect = value; return nil },
+	},
+	{
+		name:        "hooks.pre_logout",
+		description: "Comando ejecutado antes de cerrar sesión",
+		get:         func(c *Config) string { return c.Hooks.PreLogout },
+		set:         func(c *Config, value string) error { c.Hooks.PreLogout = value; return nil },
+	},
+	{
+		name:        "hooks.post_logout",
+		description: "Comando ejecutado después de cerrar sesión",
+		get:         func(c *Config) string { return c.Hooks.PostLogout },
+		set:         func(c *Config, value string) error { c.Hooks.PostLogout = value; return nil },
+	},
+	{
+		name:        "hooks.event",
+		description: "Comando ejecutado para cada evento y aviso (recibe GONAUTA_EVENT)",
+		get:         func(c *Config) string { return c.Hooks.Event },
+		set:         func(c *Config, value string) error { c.Hooks.Event = value; return nil },
+	},
+	{
+		name:        "tariff.hour_rate",
+		description: "Tarifa fija en CUP por hora para este perfil (ignora tariffs.json)",
+		get:         func(c *Config) string { return formatFloatSetting(c.Tariff.HourRate) },
+		set: func(c *Config, value string) error {
+			rate, err := parseFloatSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Tariff.HourRate = rate
+			return nil
+		},
+	},
+	{
+		name:        "tariff.account_type",
+		description: "Tipo de cuenta usado para elegir la tarifa (ej: internacional, nacional, hogar)",
+		get:         func(c *Config) string { return c.Tariff.AccountType },
+		set:         func(c *Config, value string) error { c.Tariff.AccountType = value; return nil },
+	},
+	{
+		name:        "audit.enabled",
+		description: "Medir saldo y tiempo al conectar y cerrar sesión para auditar el cobro (true o false)",
+		get:         func(c *Config) string { return formatBoolSetting(c.Audit.Enabled) },
+		set: func(c *Config, value string) error {
+			enabled, err := parseBoolSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Audit.Enabled = enabled
+			return nil
+		},
+	},
+	{
+		name:        "audit.tolerance",
+		description: "Diferencia en CUP aceptada entre el cobro esperado y el descontado (por defecto 0.5)",
+		get:         func(c *Config) string { return formatFloatSetting(c.Audit.Tolerance) },
+		set: func(c *Config, value string) error {
+			amount, err := parseFloatSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Audit.Tolerance = amount
+			return nil
+		},
+	},
+	{
+		name:        "caps.daily",
+		description: "Límite de gasto diario del perfil en CUP",
+		get:         func(c *Config) string { return formatFloatSetting(c.Caps.Daily) },
+		set: func(c *Config, value string) error {
+			amount, err := parseFloatSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Caps.Daily = amount
+			return nil
+		},
+	},
+	{
+		name:        "caps.weekly",
+		description: "Límite de gasto semanal del perfil en CUP (la semana empieza el lunes)",
+		get:         func(c *Config) string { return formatFloatSetting(c.Caps.Weekly) },
+		set: func(c *Config, value string) error {
+			amount, err := parseFloatSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Caps.Weekly = amount
+			return nil
+		},
+	},
+	{
+		name:        "caps.monthly",
+		description: "Límite de gasto mensual del perfil en CUP",
+		get:         func(c *Config) string { return formatFloatSetting(c.Caps.Monthly) },
+		set: func(c *Config, value string) error {
+			amount, err := parseFloatSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Caps.Monthly = amount
+			return nil
+		},
+	},
+	{
+		name:        "caps.mode",
+		description: "Al alcanzar un límite: block (no conectar y cerrar la sesión, por defecto) o warn (solo avisar)",
+		get:         func(c *Config) string { return c.Caps.Mode },
+		set: func(c *Config, value string) error {
+			caps := SpendingCaps{Mode: value}
+			if err := caps.validate(); err != nil {
+				return err
+			}
+			c.Caps.Mode = value
+			return nil
+		},
+	},
+	{
+		name:        "schedule.allowed_hours",
+		description: "Horas en que se permite conectar, como expresiones cron separadas por ';' (ej: * 7-17 * * 1-5)",
+		get:         func(c *Config) string { return strings.Join(c.Schedule.AllowedHours, "; ") },
+		set: func(c *Config, value string) error {
+			var exprs []string
+			for _, expr := range strings.Split(value, ";") {
+				if expr = strings.TrimSpace(expr); expr != "" {
+					exprs = append(exprs, expr)
+				}
+			}
+			if _, err := parseAllowedHours(exprs); err != nil {
+				return err
+			}
+			c.Schedule.AllowedHours = exprs
+			return nil
+		},
+	},
+	{
+		name:        "idle.timeout_minutes",
+		description: "Cerrar la sesión tras estos minutos sin tráfico (lo vigila el daemon, solo en Linux)",
+		get:         func(c *Config) string { return formatIntSetting(c.Idle.TimeoutMinutes) },
+		set: func(c *Config, value string) error {
+			minutes, err := parseIntSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Idle.TimeoutMinutes = minutes
+			return nil
+		},
+	},
+	{
+		name:        "idle.min_rate",
+		description: "Tráfico en KB/s por debajo del cual la sesión se considera inactiva (por defecto 1)",
+		get:         func(c *Config) string { return formatFloatSetting(c.Idle.MinRate) },
+		set: func(c *Config, value string) error {
+			rate, err := parseFloatSetting(value)
+			if err != nil {
+				return err
+			}
+			c.Idle.MinRate = rate
+			return nil
+		},
+	},
+	{
+		name:        "idle.interface",
+		description: "Interfaz de red vigilada (por defecto, la de la VPN si hay una activa o la Wi-Fi)",
+		get:         func(c *Config) string { return c.Idle.Interface },
+		set:         func(c *Config, value string) error { c.Idle.Interface = value; return nil },
+	},
+	{
+		name:        "traffic.interface",
+		description: "Interfaz cuyo tráfico se registra en el historial (por defecto, la Wi-Fi)",
+		get:         func(c *Config) string { return c.Traffic.Interface },
+		set:         func(c *Config, value string) error { c.Traffic.Interface = value; return nil },
+	},
+	{
+		name:        "autologin.enabled",
+		description: "Iniciar sesión al conectarse a una red de ETECSA (solo el perfil por defecto)",
+		get:         func(c *Config) string { return formatBoolSetting(c.AutoLogin.Enabled) },
+		set: func(c *Config, value string) error {
+			enabled, err := parseBoolSetting(value)
+			if err != nil {
+				return err
+			}
+			c.AutoLogin.Enabled = enabled
+			return nil
+		},
+	},
+	{
+		name:        "output.format",
+		description: "Formato de salida de status e info (text o json)",
+		get:         func(c *Config) string { return c.Output.Format },
+		set: func(c *Config, value string) error {
+			if value != "" && value != outputText && value != outputJSON {
+				return fmt.Errorf("formato inválido: %s (use text o json)", value)
+			}
+			c.Output.Format = value
+			return nil
+		},
+	},
+}
+
+// findConfigKey busca una opción por su nombre
+func findConfigKey(name string) (*configKey, error) {
+	for i := range configKeys {
+		if configKeys[i].name == name {
+			return &configKeys[i], nil
+		}
+	}
+	if name == "password" {
+		return nil, errors.New("la contraseña no se puede editar con 'gonauta config'. Use 'gonauta login'")
+	}
+	return nil, fmt.Errorf("opción desconocida: %s. Use 'gonauta config list' para ver las disponibles", name)
+}
+
+func validateURL(value string) error {
+	if value == "" {
+		return nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("URL inválida: %s", value)
+	}
+	return nil
+}
+
+func parseFloatSetting(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("valor inválido: %s (se espera un número positivo)", value)
+	}
+	return number, nil
+}
+
+func formatFloatSetting(value float64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func parseIntSetting(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("valor inválido: %s (se espera un entero positivo)", value)
+	}
+	return number, nil
+}
+
+func formatIntSetting(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+func parseBoolSetting(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("valor inválido: %s (use true o false)", value)
+	}
+	return enabled, nil
+}
+
+func formatBoolSetting(value bool) string {
+	if !value {
+		return ""
+	}
+	return "true"
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const tariffDateLayout = "2006-01-02"
+
+// Tariff es una tarifa horaria aplicable a un dominio o tipo de cuenta durante
+// un período
+type Tariff struct {
+	Name           string  `json:"name"`
+	Domain         string  `json:"domain,omitempty"`
+	AccountType    string  `json:"account_type,omitempty"`
+	HourRate       float64 `json:"hour_rate"`
+	EffectiveFrom  string  `json:"effective_from,omitempty"`
+	EffectiveUntil string  `json:"effective_until,omitempty"`
+}
+
+// TariffTable es el formato de ~/.gonauta/tariffs.json
+type TariffTable struct {
+	Tariffs []Tariff `json:"tariffs"`
+}
+
+// defaultTariffs son las tarifas usadas cuando tariffs.json no define otras
+var defaultTariffs = []Tariff{
+	{Name: "Internacional", Domain: "nauta.com.cu", AccountType: "internacional", HourRate: 12.5},
+	{Name: "Nacional", Domain: "nauta.co.cu", AccountType: "nacional", HourRate: 2.5},
+}
+
+// getTariffsPath devuelve la ruta de la tabla de tarifas compartida por todos
+// los perfiles
+func getTariffsPath() (string, error) {
+	baseDir, err := getBaseDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(baseDir, "tariffs.json"), nil
+}
+
+// LoadTariffs devuelve las tarifas de tariffs.json seguidas de las tarifas
+// por defecto. Las entradas del archivo tienen prioridad.
+func LoadTariffs() ([]Tariff, error) {
+	tariffsPath, err := getTariffsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(tariffsPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return defaultTariffs, nil
+		}
+		return nil, err
+	}
+
+	var table TariffTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("%s: %w", tariffsPath, err)
+	}
+	for _, tariff := range table.Tariffs {
+		if err := tariff.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", tariffsPath, err)
+		}
+	}
+
+	return append(table.Tariffs, defaultTariffs...), nil
+}
+
+func (t Tariff) validate() error {
+	if t.HourRate <= 0 {
+		return fmt.Errorf("tarifa %q: hour_rate debe ser mayor que cero", t.Name)
+	}
+	for _, date := range []string{t.EffectiveFrom, t.EffectiveUntil} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(tariffDateLayout, date); err != nil {
+			return fmt.Errorf("tarifa %q: fecha inválida %q (use AAAA-MM-DD)", t.Name, date)
+		}
+	}
+	return nil
+}
+
+// appliesTo indica si la tarifa corresponde al usuario, tipo de cuenta y fecha
+func (t Tariff) appliesTo(username, accountType string, at time.Time) bool {
+	if t.Domain != "" && !strings.HasSuffix(strings.ToLower(username), "@"+t.Domain) {
+		return false
+	}
+	if accountType != "" && t.AccountType != "" && t.AccountType != accountType {
+		return false
+	}
+	day := at.Format(tariffDateLayout)
+	if t.EffectiveFrom != "" && day < t.EffectiveFrom {
+		return false
+	}
+	if t.EffectiveUntil != "" && day > t.EffectiveUntil {
+		return false
+	}
+	return true
+}
+
+// resolveTariff elige la tarifa del perfil para un usuario y una fecha. El
+// valor tariff.hour_rate del perfil tiene prioridad sobre la tabla.
+func resolveTariff(config *Config, username string, at time.Time) (Tariff, error) {
+	if config.Tariff.HourRate > 0 {
+		return Tariff{Name: "Perfil " + currentProfile, HourRate: config.Tariff.HourRate}, nil
+	}
+
+	tariffs, err := LoadTariffs()
+	if err != nil {
+		return Tariff{}, err
+	}
+
+	for _, tariff := range tariffs {
+		if tariff.appliesTo(username, config.Tariff.AccountType, at) {
+			return tariff, nil
+		}
+	}
+
+	return Tariff{}, fmt.Errorf("no hay ninguna tarifa para %s el %s. Defina una en tariffs.json o use 'gonauta config set tariff.hour_rate'",
+		username, at.Format(tariffDateLayout))
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// eventCertificateChanged se lanza cuando el portal presenta un
+	// certificado que no coincide con los pines del perfil
+	eventCertificateChanged = "certificate_changed"
+
+	// pinPrefix identifica el algoritmo de los pines, con el formato de curl
+	// (--pinnedpubkey) y HPKP
+	pinPrefix = "sha256/"
+
+	pinModeEnforce = "enforce"
+	pinModeWarn    = "warn"
+)
+
+// reportedPins recuerda los certificados inesperados ya notificados por
+// perfil, host y pin, para no repetir el aviso en cada conexión
+var reportedPins sync.Map
+
+// PinMismatchError indica que el certificado del portal no coincide con
+// ninguno de los pines del perfil
+type PinMismatchError struct {
+	Host      string
+	Presented string
+}
+
+func (e *PinMismatchError) Error() string {
+	return fmt.Sprintf("el certificado de %s cambió y no coincide con los pines del perfil (presenta %s). "+
+		"Si ETECSA lo renovó, revíselo con 'gonauta tls show' y actualice el pin con 'gonauta tls pin --replace'; "+
+		"si no, la red podría estar interceptando la conexión", e.Host, e.Presented)
+}
+
+// spkiPin calcula el pin de un certificado: el SHA-256 de su clave pública
+func spkiPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return pinPrefix + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// parsePin valida un pin con el formato sha256/<base64>
+func parsePin(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	encoded, ok := strings.CutPrefix(value, pinPrefix)
+	if !ok {
+		return "", fmt.Errorf("pin inválido: %s (use el formato %s<base64>)", value, pinPrefix)
+	}
+	if sum, err := base64.StdEncoding.DecodeString(encoded); err != nil || len(sum) != sha256.Size {
+		return "", fmt.Errorf("pin inválido: %s (no es un SHA-256 en base64)", value)
+	}
+	return value, nil
+}
+
+// parsePins valida una lista de pines separados por comas
+func parsePins(value string) ([]string, error) {
+	var pins []string
+	for _, part := range strings.Split(value, ",") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		pin, err := parsePin(part)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(pins, pin) {
+			pins = append(pins, pin)
+		}
+	}
+	return pins, nil
+}
+
+// loadCAFile lee un archivo PEM con certificados de CA
+func loadCAFile(path string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo leer el archivo de CA: %w", err)
+	}
+
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s contiene un certificado inválido: %w", path, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("%s no contiene certificados PEM", path)
+	}
+	return certs, nil
+}
+
+// pinMode devuelve qué hacer si el certificado no coincide con los pines
+func (c *Config) pinMode() string {
+	if c.TLS.PinMode == "" {
+		return pinModeEnforce
+	}
+	return c.TLS.PinMode
+}
+
+// rootCAs devuelve los certificados de confianza: los del sistema más los de
+// tls.ca_file. nil significa solo los del sistema.
+func (c *Config) rootCAs() (*x509.CertPool, error) {
+	if c.TLS.CAFile == "" {
+		return nil, nil
+	}
+	certs, err := loadCAFile(c.TLS.CAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		// Sin almacén del sistema solo se confía en el archivo
+		pool = x509.NewCertPool()
+	}
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, nil
+}
+
+// tlsConfig devuelve la configuración TLS del perfil: las CA adicionales y la
+// comprobación de los pines en las conexiones al portal
+func (c *Config) tlsConfig() (*tls.Config, error) {
+	roots, err := c.rootCAs()
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{RootCAs: roots}
+
+	if len(c.TLS.Pins) > 0 {
+		portal, err := url.Parse(c.portalURL())
+		if err != nil {
+			return nil, err
+		}
+		host := portal.Hostname()
+		pins, mode := c.TLS.Pins, c.pinMode()
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			// El proxy y otros servidores no se comprueban
+			if state.ServerName != host {
+				return nil
+			}
+			return c.checkPins(host, state, pins, mode)
+		}
+	}
+	return config, nil
+}
+
+// checkPins comprueba que algún certificado de la cadena verificada coincida
+// con los pines. Con pin_mode warn solo avisa.
+func (c *Config) checkPins(host string, state tls.ConnectionState, pins []string, mode string) error {
+	chains := state.VerifiedChains
+	if len(chains) == 0 {
+		chains = [][]*x509.Certificate{state.PeerCertificates}
+	}
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if slices.Contains(pins, spkiPin(cert)) {
+				return nil
+			}
+		}
+	}
+
+	presented := spkiPin(state.PeerCertificates[0])
+	mismatch := &PinMismatchError{Host: host, Presented: presented}
+	key := currentProfile + "\x00" + host + "\x00" + presented
+	if _, reported := reportedPins.LoadOrStore(key, true); !reported {
+		fireEvent(c, eventCertificateChanged, map[string]string{
+			"HOST":    host,
+			"PIN":     presented,
+			"MESSAGE": mismatch.Error(),
+		})
+		if mode == pinModeWarn {
+			// Se avisa por stderr: la comprobación ocurre en mitad de una
+			// petición y no debe mezclarse con la salida JSON
+			fmt.Fprintf(os.Stderr, "⚠️  Advertencia: %v\n", mismatch)
+		}
+	}
+	if mode == pinModeWarn {
+		return nil
+	}
+	return mismatch
+}
+
+// fetchPortalCertificates se conecta al portal sin comprobar los pines ni la
+// cadena y devuelve los certificados presentados, para poder revisarlos
+// aunque no sean de confianza
+func fetchPortalCertificates(config *Config) (*tls.ConnectionState, error) {
+	portal, err := url.Parse(config.portalURL())
+	if err != nil {
+		return nil, err
+	}
+	if portal.Scheme != "https" {
+		return nil, fmt.Errorf("el portal %s no usa https", config.portalURL())
+	}
+
+	unpinned := *config
+	unpinned.TLS.Pins = nil
+	transport, err := newBaseTransport(&unpinned)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig.InsecureSkipVerify = true
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   MaxTimeoutSeconds * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Head(portal.String())
+	if err != nil {
+		return nil, fmt.Errorf("error de conexión: %w", err)
+	}
+	resp.Body.Close()
+	if resp.TLS == nil || len(resp.TLS.PeerCertificates) == 0 {
+		return nil, errors.New("el portal no presentó ningún certificado")
+	}
+	return resp.TLS, nil
+}
+
+// verifyPortalChain comprueba la cadena presentada con las CA de confianza
+func verifyPortalChain(config *Config, state *tls.ConnectionState) error {
+	roots, err := config.rootCAs()
+	if err != nil {
+		return err
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       state.ServerName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+func printTLSUsage() {
+	fmt.Println("Uso: gonauta tls <subcomando>")
+	fmt.Println("\nSubcomandos:")
+	fmt.Println("  show                   Mostrar el certificado del portal y sus pines")
+	fmt.Println("  pin [--replace] [pin]  Fijar el certificado actual del portal, o el pin indicado")
+	fmt.Println("\nCon pines, 'connect' y el resto de comandos rechazan un certificado que no")
+	fmt.Println("coincida (o solo avisan con 'gonauta config set tls.pin_mode warn').")
+}
+
+func handleTLS(args []string) {
+	if len(args) == 0 {
+		printTLSUsage()
+		os.Exit(1)
+	}
+
+	config, err := loadConfigOrEmpty()
+	if err != nil {
+		fmt.Printf("Error cargando configuración: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "show":
+		state, err := fetchPortalCertificates(config)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Certificados de %s:\n", state.ServerName)
+		for i, cert := range state.PeerCertificates {
+			pin := spkiPin(cert)
+			mark := ""
+			if slices.Contains(config.TLS.Pins, pin) {
+				mark = "  ✓ fijado"
+			}
+			fmt.Printf("\n[%d] %s\n", i, cert.Subject.CommonName)
+			fmt.Printf("    Emisor: %s\n", cert.Issuer.CommonName)
+			fmt.Printf("    Válido: %s a %s\n", cert.NotBefore.Format("2006-01-02"), cert.NotAfter.Format("2006-01-02"))
+			fmt.Printf("    Pin:    %s%s\n", pin, mark)
+		}
+
+		fmt.Println()
+		if err := verifyPortalChain(config, state); err != nil {
+			fmt.Printf("Cadena de confianza: ✗ %v\n", err)
+		} else {
+			fmt.Println("Cadena de confianza: ✓ válida")
+		}
+		if len(config.TLS.Pins) > 0 {
+			matched := slices.ContainsFunc(state.PeerCertificates, func(cert *x509.Certificate) bool {
+				return slices.Contains(config.TLS.Pins, spkiPin(cert))
+			})
+			if matched {
+				fmt.Println("Pines: ✓ coincide")
+			} else {
+				fmt.Printf("Pines: ✗ ninguno coincide (modo %s)\n", config.pinMode())
+			}
+		}
+
+	case "pin":
+		fs := flag.NewFlagSet("tls pin", flag.ContinueOnError)
+		replace := fs.Bool("replace", false, "sustituir los pines del perfil en lugar de añadir")
+		parseCommandFlags(fs, args[1:])
+
+		var pins []string
+		if fs.NArg() > 0 {
+			if pins, err = parsePins(strings.Join(fs.Args(), ",")); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			state, err := fetchPortalCertificates(config)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			// Solo se fija un certificado de confianza: fijar uno falso
+			// protegería al atacante
+			if err := verifyPortalChain(config, state); err != nil {
+				fmt.Printf("Error: el certificado de %s no es de confianza: %v\n", state.ServerName, err)
+				fmt.Println("Si el portal usa una CA que este equipo no conoce, configúrela con 'gonauta config set tls.ca_file <archivo.pem>'")
+				os.Exit(1)
+			}
+			leaf := state.PeerCertificates[0]
+			fmt.Printf("Certificado: %s (emisor: %s, válido hasta %s)\n", leaf.Subject.CommonName, leaf.Issuer.CommonName, leaf.NotAfter.Format("2006-01-02"))
+			pins = []string{spkiPin(leaf)}
+		}
+
+		if !*replace {
+			pins, _ = parsePins(strings.Join(append(config.TLS.Pins, pins...), ","))
+		}
+		config.TLS.Pins = pins
+		if err := SaveConfig(config); err != nil {
+			fmt.Printf("Error guardando configuración: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("✓ Pines del perfil:")
+		for _, pin := range pins {
+			fmt.Printf("  %s\n", pin)
+		}
+
+	case "help", "-h", "--help":
+		printTLSUsage()
+
+	default:
+		fmt.Printf("Subcomando desconocido: %s\n\n", args[0])
+		printTLSUsage()
+		os.Exit(1)
+	}
+}
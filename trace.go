@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// redactedValue sustituye los datos sensibles en las trazas
+	redactedValue = "REDACTED"
+
+	// minSecretLength evita ocultar en todo el texto valores demasiado cortos,
+	// que aparecerían por casualidad; el campo en sí se oculta igualmente
+	minSecretLength = 4
+)
+
+// tracer registra las peticiones HTTP cuando se usa --trace
+var tracer *harRecorder
+
+// sensitiveFields son los campos de formulario y de URL que se ocultan
+var sensitiveFields = map[string]bool{
+	"password":       true,
+	"ATTRIBUTE_UUID": true,
+}
+
+// sensitiveHeaders son las cabeceras cuyo valor se oculta
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// harRecorder acumula las peticiones y reescribe el archivo HAR tras cada una,
+// para que la traza esté completa aunque el programa termine con os.Exit
+type harRecorder struct {
+	mu      sync.Mutex
+	path    string
+	redact  bool
+	secrets []string
+	har     harFile
+	failed  bool
+}
+
+// newHARRecorder prepara la traza en path. Si redact es true, oculta las
+// contraseñas, los UUID de sesión y las cookies.
+func newHARRecorder(path string, redact bool) *harRecorder {
+	version := "dev"
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		version = info.Main.Version
+	}
+	return &harRecorder{
+		path:   path,
+		redact: redact,
+		har: harFile{Log: harLog{
+			Version: "1.2",
+			Creator: harCreator{Name: "gonauta", Version: version},
+			Entries: []harEntry{},
+		}},
+	}
+}
+
+// tracingTransport registra en un harRecorder cada petición y su respuesta
+type tracingTransport struct {
+	base     http.RoundTripper
+	recorder *harRecorder
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		t.recorder.add(req, reqBody, nil, nil, start, err)
+		return nil, err
+	}
+
+	// Leer la respuesta completa para guardarla y devolver una copia
+	respBody, readErr := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(respBody), errorReader{readErr}))
+	t.recorder.add(req, reqBody, resp, respBody, start, readErr)
+	return resp, nil
+}
+
+// errorReader devuelve err al leer, o EOF si err es nil
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read([]byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	return 0, io.EOF
+}
+
+// add guarda una petición en la traza
+func (r *harRecorder) add(req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, start time.Time, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elapsed := float64(time.Since(start).Microseconds()) / 1000
+	entry := harEntry{
+		StartedDateTime: start,
+		Time:            elapsed,
+		Request:         r.request(req, reqBody),
+		Timings:         harTimings{Send: 0, Wait: elapsed, Receive: 0},
+	}
+	if resp != nil {
+		entry.Response = r.response(resp, respBody)
+	} else {
+		entry.Response = harResponse{Cookies: []harNameValue{}, Headers: []harNameValue{}, HeadersSize: -1, BodySize: -1}
+	}
+	if err != nil {
+		entry.Comment = r.scrub(err.Error())
+	}
+
+	r.har.Log.Entries = append(r.har.Log.Entries, entry)
+	r.save()
+}
+
+// request convierte una petición en una entrada HAR, recordando sus valores
+// sensibles para ocultarlos también en las respuestas
+func (r *harRecorder) request(req *http.Request, body []byte) harRequest {
+	query := req.URL.Query()
+	r.remember(query)
+
+	entry := harRequest{
+		Method:      req.Method,
+		HTTPVersion: req.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     r.headers(req.Header),
+		QueryString: r.params(query),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+
+	if len(body) > 0 {
+		mimeType := req.Header.Get("Content-Type")
+		postData := &harPostData{MimeType: mimeType, Text: string(body)}
+		if mediaType, _, _ := mime.ParseMediaType(mimeType); mediaType == "application/x-www-form-urlencoded" {
+			if form, err := url.ParseQuery(string(body)); err == nil {
+				r.remember(form)
+				postData.Params = r.params(form)
+				if r.redact {
+					postData.Text = r.redactValues(form).Encode()
+				}
+			}
+		}
+		postData.Text = r.scrub(postData.Text)
+		entry.PostData = postData
+	}
+
+	u := *req.URL
+	if r.redact {
+		u.RawQuery = r.redactValues(query).Encode()
+	}
+	entry.URL = u.String()
+	return entry
+}
+
+// response convierte una respuesta en una entrada HAR
+func (r *harRecorder) response(resp *http.Response, body []byte) harResponse {
+	entry := harResponse{
+		Status:      resp.StatusCode,
+		StatusText:  strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode))),
+		HTTPVersion: resp.Proto,
+		Cookies:     []harNameValue{},
+		Headers:     r.headers(resp.Header),
+		RedirectURL: r.scrub(resp.Header.Get("Location")),
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	entry.Content.MimeType = resp.Header.Get("Content-Type")
+	entry.Content.setBody([]byte(r.scrub(string(body))))
+	return entry
+}
+
+// remember guarda los valores de los campos sensibles de una petición
+func (r *harRecorder) remember(values url.Values) {
+	if !r.redact {
+		return
+	}
+	for name, list := range values {
+		if !sensitiveFields[name] {
+			continue
+		}
+		for _, value := range list {
+			if len(value) >= minSecretLength {
+				r.secrets = append(r.secrets, value)
+			}
+		}
+	}
+}
+
+// redactValues devuelve una copia con los campos sensibles ocultos
+func (r *harRecorder) redactValues(values url.Values) url.Values {
+	redacted := url.Values{}
+	for name, list := range values {
+		for _, value := range list {
+			if sensitiveFields[name] && value != "" {
+				value = redactedValue
+			}
+			redacted.Add(name, value)
+		}
+	}
+	return redacted
+}
+
+// params convierte los campos de un formulario o de la URL en pares HAR
+func (r *harRecorder) params(values url.Values) []harNameValue {
+	if r.redact {
+		values = r.redactValues(values)
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := []harNameValue{}
+	for _, name := range names {
+		for _, value := range values[name] {
+			pairs = append(pairs, harNameValue{Name: name, Value: value})
+		}
+	}
+	return pairs
+}
+
+// headers convierte las cabeceras ocultando las sensibles
+func (r *harRecorder) headers(header http.Header) []harNameValue {
+	pairs := harHeaders(header)
+	if !r.redact {
+		return pairs
+	}
+	for i := range pairs {
+		if sensitiveHeaders[http.CanonicalHeaderKey(pairs[i].Name)] {
+			pairs[i].Value = redactedValue
+		} else {
+			pairs[i].Value = r.scrub(pairs[i].Value)
+		}
+	}
+	return pairs
+}
+
+// scrub oculta en un texto los UUID de sesión y los valores sensibles vistos
+// en peticiones anteriores
+func (r *harRecorder) scrub(text string) string {
+	if !r.redact {
+		return text
+	}
+	text = redactUUIDs(text)
+	for _, secret := range r.secrets {
+		text = strings.ReplaceAll(text, secret, redactedValue)
+		text = strings.ReplaceAll(text, url.QueryEscape(secret), redactedValue)
+	}
+	return text
+}
+
+// save reescribe el archivo HAR. Solo avisa del primer error.
+func (r *harRecorder) save() {
+	data, err := json.MarshalIndent(r.har, "", "  ")
+	if err == nil {
+		err = os.WriteFile(r.path, data, 0600)
+	}
+	if err != nil && !r.failed {
+		r.failed = true
+		fmt.Printf("Advertencia: No se pudo guardar la traza en %s: %v\n", r.path, err)
+	}
+}
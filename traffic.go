@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// trafficSnapshotInterval es cada cuánto el daemon guarda el tráfico de la
+// sesión activa
+const trafficSnapshotInterval = 5 * time.Minute
+
+// SessionTraffic es el tráfico acumulado de una sesión en una interfaz
+type SessionTraffic struct {
+	Interface string            `json:"interface"`
+	RxBytes   uint64            `json:"rx_bytes"`
+	TxBytes   uint64            `json:"tx_bytes"`
+	Last      InterfaceCounters `json:"last"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// add suma el tráfico desde la lectura anterior. Si los contadores se
+// reiniciaron, se cuenta todo lo leído desde el reinicio.
+func (t *SessionTraffic) add(counters InterfaceCounters, now time.Time) {
+	delta, ok := counters.Sub(t.Last)
+	if !ok {
+		delta = counters
+	}
+	t.RxBytes += delta.RxBytes
+	t.TxBytes += delta.TxBytes
+	t.Last = counters
+	t.UpdatedAt = now
+}
+
+// Total devuelve los bytes recibidos y enviados en la sesión
+func (t *SessionTraffic) Total() uint64 {
+	return t.RxBytes + t.TxBytes
+}
+
+// measureTraffic devuelve el tráfico de la sesión actualizado con los
+// contadores actuales, sin guardarlo. Si la sesión todavía no tiene tráfico
+// registrado, empieza a contar desde ahora en la interfaz del perfil.
+func measureTraffic(config *Config, record *SessionRecord, now time.Time) (*SessionTraffic, error) {
+	if record.Traffic == nil {
+		iface, counters, err := selectInterface(config.Traffic.Interface, false)
+		if err != nil {
+			return nil, err
+		}
+		return &SessionTraffic{Interface: iface, Last: counters, UpdatedAt: now}, nil
+	}
+
+	counters, err := readInterfaceCounters()
+	if err != nil {
+		return nil, err
+	}
+	current, ok := counters[record.Traffic.Interface]
+	if !ok {
+		return nil, fmt.Errorf("la interfaz %s ya no existe", record.Traffic.Interface)
+	}
+
+	traffic := *record.Traffic
+	traffic.add(current, now)
+	return &traffic, nil
+}
+
+// snapshotTraffic guarda en el historial el tráfico actual de una sesión. Los
+// errores se ignoran: el tráfico es informativo y no está disponible en todos
+// los sistemas.
+func snapshotTraffic(config *Config, recordID string) {
+	record := findSessionRecord(recordID)
+	if record == nil {
+		return
+	}
+	traffic, err := measureTraffic(config, record, time.Now())
+	if err != nil {
+		return
+	}
+	updateSessionRecord(recordID, func(record *SessionRecord) { record.Traffic = traffic })
+}
+
+// checkTraffic guarda periódicamente el tráfico de la sesión activa del perfil
+func checkTraffic(d *daemon, config *Config, now time.Time) {
+	sessionData, err := LoadSession()
+	if err != nil || sessionData.RecordID == "" {
+		return
+	}
+	if !d.due("traffic", trafficSnapshotInterval, now) {
+		return
+	}
+	snapshotTraffic(config, sessionData.RecordID)
+}
+
+// formatBytes muestra una cantidad de bytes en la unidad más adecuada
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value := float64(n) / unit
+	for _, suffix := range []string{"KB", "MB", "GB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f TB", value)
+}
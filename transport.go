@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+)
+
+// newBaseTransport devuelve el transporte HTTP con el proxy, la
+// configuración TLS y la resolución de nombres del perfil
+func newBaseTransport(config *Config) (*http.Transport, error) {
+	tlsConfig, err := config.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	dial, err := config.dialContext()
+	if err != nil {
+		return nil, err
+	}
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.Proxy = config.proxyFunc()
+	base.OnProxyConnectResponse = checkProxyConnect
+	base.TLSClientConfig = tlsConfig
+	if dial != nil {
+		base.DialContext = dial
+	}
+	return base, nil
+}
+
+// newTransport devuelve el transporte HTTP que usan el cliente del portal y la
+// detección del estado de la red. Con --trace registra cada petición.
+func newTransport(config *Config) (http.RoundTripper, error) {
+	base, err := newBaseTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var transport http.RoundTripper = base
+	if tracer != nil {
+		transport = &tracingTransport{base: transport, recorder: tracer}
+	}
+	return transport, nil
+}
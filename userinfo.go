@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AccountStatus es el estado de la cuenta informado por el portal
+type AccountStatus string
+
+const (
+	StatusActive    AccountStatus = "Active"
+	StatusDisabled  AccountStatus = "Disabled"
+	StatusBlocked   AccountStatus = "Blocked"
+	StatusSuspended AccountStatus = "Suspended"
+	StatusExpired   AccountStatus = "Expired"
+)
+
+// accountStatusTexts asocia los textos del portal, normalizados (ver
+// normalizeText), con su estado
+var accountStatusTexts = map[string]AccountStatus{
+	"activa":         StatusActive,
+	"activo":         StatusActive,
+	"deshabilitada":  StatusDisabled,
+	"deshabilitado":  StatusDisabled,
+	"inactiva":       StatusDisabled,
+	"inactivo":       StatusDisabled,
+	"bloqueada":      StatusBlocked,
+	"bloqueado":      StatusBlocked,
+	"suspendida":     StatusSuspended,
+	"suspendido":     StatusSuspended,
+	"expirada":       StatusExpired,
+	"expirado":       StatusExpired,
+	"vencida":        StatusExpired,
+	"vencido":        StatusExpired,
+	"cuenta vencida": StatusExpired,
+}
+
+// parseAccountStatus convierte el texto del portal en un estado. Los textos
+// desconocidos se tratan como cuenta deshabilitada, igual que antes.
+func parseAccountStatus(text string) AccountStatus {
+	if status, ok := accountStatusTexts[normalizeText(text)]; ok {
+		return status
+	}
+	return StatusDisabled
+}
+
+// AccessScope es el tipo de acceso de la cuenta
+type AccessScope string
+
+const (
+	AccessAll           AccessScope = "All"
+	AccessNational      AccessScope = "National"
+	AccessInternational AccessScope = "International"
+	AccessUnknown       AccessScope = "Unknown"
+)
+
+// accessScopeTexts asocia fragmentos normalizados de los textos del portal
+// con su tipo
+var accessScopeTexts = []struct {
+	fragment string
+	scope    AccessScope
+}{
+	{"todas las areas", AccessAll},
+	{"internacional", AccessInternational},
+	{"nacional", AccessNational},
+}
+
+// AccessInfo contiene el tipo de acceso y el texto original del portal
+type AccessInfo struct {
+	Scope AccessScope
+	Text  string
+}
+
+// parseAccessInfo clasifica el texto de tipo de acceso del portal
+func parseAccessInfo(text string) AccessInfo {
+	text = strings.TrimSpace(text)
+	normalized := normalizeText(text)
+	for _, candidate := range accessScopeTexts {
+		if strings.Contains(normalized, candidate.fragment) {
+			return AccessInfo{Scope: candidate.scope, Text: text}
+		}
+	}
+	return AccessInfo{Scope: AccessUnknown, Text: text}
+}
+
+// String devuelve "All" para el acceso completo, como en versiones
+// anteriores, y el texto del portal en los demás casos
+func (a AccessInfo) String() string {
+	if a.Scope == AccessAll || a.Text == "" {
+		return string(a.Scope)
+	}
+	return a.Text
+}
+
+// expirationLayouts son los formatos de fecha usados por el portal
+var expirationLayouts = []string{
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"02/01/2006",
+	"2006-01-02",
+}
+
+// havanaLocation devuelve la zona horaria de Cuba. Si el sistema no tiene la
+// base de datos de zonas horarias se usa UTC-5.
+func havanaLocation() *time.Location {
+	location, err := time.LoadLocation("America/Havana")
+	if err != nil {
+		return time.FixedZone("CST", -5*60*60)
+	}
+	return location
+}
+
+// parseExpirationDate interpreta la fecha de expiración del portal en la hora
+// de Cuba. Devuelve nil si la cuenta no tiene fecha de expiración.
+func parseExpirationDate(text string) (*time.Time, error) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.EqualFold(text, "No especificada") || text == "None" {
+		return nil, nil
+	}
+
+	for _, layout := range expirationLayouts {
+		if date, err := time.ParseInLocation(layout, text, havanaLocation()); err == nil {
+			return &date, nil
+		}
+	}
+	return nil, fmt.Errorf("fecha de expiración con formato desconocido: %q", text)
+}
+
+// expiration devuelve la fecha de expiración para mostrarla: la fecha
+// reconocida o, si no se reconoció, el texto del portal
+func (u *UserInfo) expiration() string {
+	if u.ExpirationDate == nil && u.ExpirationText != "" {
+		return u.ExpirationText
+	}
+	return formatExpirationDate(u.ExpirationDate)
+}
+
+// expirationNote devuelve la advertencia que acompaña a la fecha de expiración
+// cuando se muestra el texto del portal, o "" si la fecha se reconoció
+func (u *UserInfo) expirationNote() string {
+	if u.ExpirationDate == nil && u.ExpirationText != "" {
+		return " (formato no reconocido; se muestra el texto del portal)"
+	}
+	return ""
+}
+
+// formatExpirationDate formatea una fecha de expiración como la muestra el
+// portal, o "None" si no tiene
+func formatExpirationDate(date *time.Time) string {
+	if date == nil {
+		return "None"
+	}
+	local := date.In(havanaLocation())
+	if local.Hour() == 0 && local.Minute() == 0 && local.Second() == 0 {
+		return local.Format("02/01/2006")
+	}
+	return local.Format("02/01/2006 15:04:05")
+}
+
+// durationToTime convierte una duración al formato JSON de versiones anteriores
+func durationToTime(d time.Duration) Time {
+	d = d.Truncate(time.Second)
+	return Time{
+		Hours:   int(d.Hours()),
+		Minutes: int(d.Minutes()) % 60,
+		Seconds: int(d.Seconds()) % 60,
+	}
+}
+
+// Duration convierte el formato JSON de versiones anteriores en una duración
+func (t Time) Duration() time.Duration {
+	return time.Duration(t.Hours)*time.Hour +
+		time.Duration(t.Minutes)*time.Minute +
+		time.Duration(t.Seconds)*time.Second
+}
+
+// userInfoJSON es la representación JSON de UserInfo, compatible con la de
+// versiones anteriores
+type userInfoJSON struct {
+	Status         AccountStatus `json:"status"`
+	Credits        float64       `json:"credits"`
+	ExpirationDate string        `json:"expiration_date"`
+	AccessInfo     string        `json:"access_info"`
+	RemainingTime  Time          `json:"remaining_time"`
+}
+
+// MarshalJSON mantiene el formato JSON de versiones anteriores
+func (u UserInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(userInfoJSON{
+		Status:         u.Status,
+		Credits:        u.Credits,
+		ExpirationDate: u.expiration(),
+		AccessInfo:     u.AccessInfo.String(),
+		RemainingTime:  durationToTime(u.RemainingTime),
+	})
+}
+
+// UnmarshalJSON lee el formato JSON de versiones anteriores
+func (u *UserInfo) UnmarshalJSON(data []byte) error {
+	var raw userInfoJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	// Una fecha con formato desconocido se conserva como texto
+	expiration, err := parseExpirationDate(raw.ExpirationDate)
+	expirationText := ""
+	if err != nil {
+		expirationText = strings.TrimSpace(raw.ExpirationDate)
+	}
+
+	access := parseAccessInfo(raw.AccessInfo)
+	if AccessScope(raw.AccessInfo) == AccessAll {
+		access = AccessInfo{Scope: AccessAll}
+	}
+
+	*u = UserInfo{
+		Status:         raw.Status,
+		Credits:        raw.Credits,
+		ExpirationDate: expiration,
+		ExpirationText: expirationText,
+		AccessInfo:     access,
+		RemainingTime:  raw.RemainingTime.Duration(),
+	}
+	return nil
+}